@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	APG_MAX_PERIOD = 1000 // Default number of generations to search for the period of an object
+	apgStripHeight = 5    // Each character in a strip represents a column of 5 cells
+	apgDigits      = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+// Encode a list of x,y coords in to the Extended Wechsler Format used by apgcodes.
+// The coords are normalised first (see POCNormaliseCoords) so the orientation is as given.
+//
+//	The pattern is cut in to strips 5 cells high. Each column in a strip is a character 0..v (bit 0 is the top cell).
+//	Strips are separated by 'z'. Trailing empty columns in a strip are dropped.
+//	Runs of empty columns are compressed 'w' = 00, 'x' = 000, 'y'c = 4 + c (0..z) zeros.
+func ApgWechslerEncode(coords []int64) string {
+	if len(coords) == 0 {
+		return "0"
+	}
+	co, w, h := POCNormaliseCoords(coords)
+	strips := int((h + apgStripHeight - 1) / apgStripHeight)
+	columns := make([][]byte, strips)
+	for i := range columns {
+		columns[i] = make([]byte, w)
+	}
+	for i := 0; i < len(co); i = i + 2 {
+		columns[co[i+1]/apgStripHeight][co[i]] |= 1 << (co[i+1] % apgStripHeight)
+	}
+	var sb strings.Builder
+	for s, col := range columns {
+		if s > 0 {
+			sb.WriteByte('z')
+		}
+		zeros := 0
+		for _, v := range col {
+			if v == 0 {
+				zeros++
+				continue
+			}
+			apgWriteZeros(&sb, zeros)
+			zeros = 0
+			sb.WriteByte(apgDigits[v])
+		}
+	}
+	return sb.String()
+}
+
+// Write a run of empty columns using the shortest form.
+func apgWriteZeros(sb *strings.Builder, n int) {
+	for n > 39 {
+		sb.WriteString("yz")
+		n = n - 39
+	}
+	switch {
+	case n == 0:
+	case n == 1:
+		sb.WriteByte('0')
+	case n == 2:
+		sb.WriteByte('w')
+	case n == 3:
+		sb.WriteByte('x')
+	default:
+		sb.WriteByte('y')
+		sb.WriteByte(apgDigits[n-4])
+	}
+}
+
+// Decode an Extended Wechsler Format string in to a list of x,y coords.
+func ApgWechslerDecode(code string) ([]int64, error) {
+	return apgWechslerDecode(code, 0)
+}
+
+// Decode the Extended Wechsler part of an apgcode.
+// ofs is the position of the first character in the full apgcode so errors report the correct position.
+func apgWechslerDecode(code string, ofs int) ([]int64, error) {
+	coords := make([]int64, 0)
+	var x, y int64 = 0, 0
+	for i := ofs; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case c == 'z':
+			x = 0
+			y = y + apgStripHeight
+		case c == 'w':
+			x = x + 2
+		case c == 'x':
+			x = x + 3
+		case c == 'y':
+			i++
+			if i >= len(code) {
+				return nil, fmt.Errorf("apgcode '%s' ends with 'y'. Expected a count 0..z", code)
+			}
+			n := strings.IndexByte(apgDigits, code[i])
+			if n < 0 {
+				return nil, fmt.Errorf("apgcode '%s' has invalid count '%c' after 'y' at position %d", code, code[i], i+1)
+			}
+			x = x + int64(n) + 4
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'v':
+			v := strings.IndexByte(apgDigits, c)
+			for b := int64(0); b < apgStripHeight; b++ {
+				if v&(1<<b) != 0 {
+					coords = append(coords, x, y+b)
+				}
+			}
+			x++
+		default:
+			return nil, fmt.Errorf("apgcode '%s' has invalid character '%c' at position %d", code, c, i+1)
+		}
+	}
+	return coords, nil
+}
+
+// Decode an apgcode, for example 'xq4_153' (a glider), in to a list of x,y coords.
+// The prefix (xs, xp or xq) is checked but not used. A plain Extended Wechsler string is also accepted.
+func ApgCodeDecode(code string) ([]int64, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, fmt.Errorf("apgcode is empty")
+	}
+	us := strings.IndexByte(code, '_')
+	if us >= 0 {
+		prefix := code[:us]
+		if len(prefix) < 3 || prefix[0] != 'x' || !strings.ContainsRune("spq", rune(prefix[1])) {
+			return nil, fmt.Errorf("apgcode '%s' has unsupported prefix '%s'. Expected xs, xp or xq", code, prefix)
+		}
+		if _, err := strconv.Atoi(prefix[2:]); err != nil {
+			return nil, fmt.Errorf("apgcode '%s' prefix '%s' does not end with a number", code, prefix)
+		}
+	}
+	return apgWechslerDecode(code, us+1)
+}
+
+// Return true if apgcode a comes before b.
+// Shorter codes come first, codes of the same length are compared as strings.
+func apgLess(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// Find the orientation that gives the smallest Extended Wechsler string.
+// Returns the string and the symmetry that was applied to the coords to get it.
+func ApgCanonicalWechsler(coords []int64) (string, LifeSymmetry) {
+	best := ""
+	bestSym := SYM_NONE
+	for s := SYM_NONE; s < SYM_COUNT; s++ {
+		co, _, _ := TransformCoords(coords, s)
+		code := ApgWechslerEncode(co)
+		if best == "" || apgLess(code, best) {
+			best = code
+			bestSym = s
+		}
+	}
+	return best, bestSym
+}
+
+// Run the pattern for up to maxGen generations to find its period.
+// Returns the period (0 if not found), the distance moved in one period and the cells for each phase.
+func apgClassify(coords []int64, maxGen int) (int, int64, int64, [][]int64) {
+	return apgClassifyLimit(coords, maxGen, 0)
+}
+
+// As apgClassify but gives up (period 0) if the population grows above maxCells. 0 is no limit.
+func apgClassifyLimit(coords []int64, maxGen, maxCells int) (int, int64, int64, [][]int64) {
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	lg.AddCellsAtOffset(0, 0, 0, coords)
+	start := lg.ListCellsWithMode(0)
+	startNorm, _, _ := POCNormaliseCoords(start)
+	startX, startY := apgMinXY(start)
+	phases := [][]int64{start}
+	for p := 1; p <= maxGen; p++ {
+		lg.NextGen()
+		cells := lg.ListCellsWithMode(0)
+		if len(cells) == 0 || (maxCells > 0 && len(cells) > maxCells*2) {
+			return 0, 0, 0, nil
+		}
+		if len(cells) == len(start) {
+			norm, _, _ := POCNormaliseCoords(cells)
+			if apgEqual(norm, startNorm) {
+				x, y := apgMinXY(cells)
+				return p, x - startX, y - startY, phases
+			}
+		}
+		phases = append(phases, cells)
+	}
+	return 0, 0, 0, nil
+}
+
+// Encode a list of x,y coords as a canonical apgcode.
+//
+//	Still lifes are 'xs' + population, oscillators 'xp' + period and spaceships 'xq' + period.
+//	The code is the smallest Extended Wechsler string over all phases and orientations.
+//
+// An error is returned if the pattern dies or does not repeat within maxGen generations.
+func ApgCodeEncode(coords []int64, maxGen int) (string, error) {
+	if len(coords) == 0 {
+		return "xs0_0", nil
+	}
+	period, dx, dy, phases := apgClassify(coords, maxGen)
+	if period == 0 {
+		return "", fmt.Errorf("pattern does not repeat within %d generations", maxGen)
+	}
+	best := ""
+	for _, ph := range phases {
+		code, _ := ApgCanonicalWechsler(ph)
+		if best == "" || apgLess(code, best) {
+			best = code
+		}
+	}
+	switch {
+	case dx != 0 || dy != 0:
+		return fmt.Sprintf("xq%d_%s", period, best), nil
+	case period == 1:
+		return fmt.Sprintf("xs%d_%s", len(phases[0])/2, best), nil
+	}
+	return fmt.Sprintf("xp%d_%s", period, best), nil
+}
+
+func apgMinXY(coords []int64) (int64, int64) {
+	minx := coords[0]
+	miny := coords[1]
+	for i := 2; i < len(coords); i = i + 2 {
+		if coords[i] < minx {
+			minx = coords[i]
+		}
+		if coords[i+1] < miny {
+			miny = coords[i+1]
+		}
+	}
+	return minx, miny
+}
+
+// Compare two lists of coords. The order of the cells is not important.
+func apgEqual(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sa := apgSortedInd(a)
+	sb := apgSortedInd(b)
+	for i := range sa {
+		if sa[i] != sb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func apgSortedInd(coords []int64) []int64 {
+	ind := make([]int64, len(coords)/2)
+	for i := 0; i < len(coords); i = i + 2 {
+		ind[i/2] = coords[i]*indexMult + coords[i+1]
+	}
+	sort.Slice(ind, func(i, j int) bool { return ind[i] < ind[j] })
+	return ind
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApgCodeEncode(t *testing.T) {
+	testApgEncode(t, "Block", []int64{0, 0, 1, 0, 0, 1, 1, 1}, "xs4_33")
+	testApgEncode(t, "Beehive", []int64{1, 0, 2, 0, 0, 1, 3, 1, 1, 2, 2, 2}, "xs6_696")
+	testApgEncode(t, "Blinker", []int64{0, 0, 1, 0, 2, 0}, "xp2_7")
+	testApgEncode(t, "Glider", []int64{1, 0, 2, 1, 0, 2, 1, 2, 2, 2}, "xq4_153")
+	testApgEncode(t, "Empty", []int64{}, "xs0_0")
+	rle, err := NewRleFile("testdata/blinker.rle")
+	if err != nil {
+		t.Errorf("RLE File load failed. %e", err)
+	}
+	testApgEncode(t, "Blinker RLE", rle.coords, "xp2_7")
+
+	_, err = ApgCodeEncode([]int64{0, 0}, APG_MAX_PERIOD)
+	if err == nil {
+		t.Errorf("ApgCodeEncode: Expected an error for a single cell")
+	}
+}
+
+func TestApgCodeDecode(t *testing.T) {
+	for _, code := range []string{"xs4_33", "xs6_696", "xp2_7", "xq4_153", "xq4_6frc", "xp2_7e", "xs5_253", "xs7_2596", "xs4_252"} {
+		coords, err := ApgCodeDecode(code)
+		if err != nil {
+			t.Errorf("ApgCodeDecode: '%s' returned error %s", code, err.Error())
+			continue
+		}
+		testApgEncode(t, "Decode "+code, coords, code)
+	}
+	coords, err := ApgCodeDecode("x3yaw1")
+	if err != nil {
+		t.Errorf("ApgCodeDecode: returned error %s", err.Error())
+	}
+	if !apgEqual(coords, []int64{3, 0, 3, 1, 20, 0}) {
+		t.Errorf("ApgCodeDecode: Expected '3,0 3,1 20,0' actual %v", coords)
+	}
+	testApgDecodeError(t, "xq4_15!", "invalid character '!' at position 7")
+	testApgDecodeError(t, "xq4_15y", "ends with 'y'")
+	testApgDecodeError(t, "ov_153", "unsupported prefix 'ov'")
+	testApgDecodeError(t, "xqa_153", "does not end with a number")
+	testApgDecodeError(t, " ", "apgcode is empty")
+}
+
+func TestApgWechslerEncode(t *testing.T) {
+	// Two cells 40 columns apart need 'yz' (39 zeros) and a single '0'
+	enc := ApgWechslerEncode([]int64{0, 0, 41, 0})
+	if enc != "1yz01" {
+		t.Errorf("ApgWechslerEncode: Expected '1yz01' actual '%s'", enc)
+	}
+	dec, _ := ApgWechslerDecode(enc)
+	if !apgEqual(dec, []int64{0, 0, 41, 0}) {
+		t.Errorf("ApgWechslerDecode: Expected '0,0 41,0' actual %v", dec)
+	}
+	// Two strips with an empty strip between them
+	enc = ApgWechslerEncode([]int64{0, 0, 0, 10})
+	if enc != "1zz1" {
+		t.Errorf("ApgWechslerEncode: Expected '1zz1' actual '%s'", enc)
+	}
+}
+
+func testApgEncode(t *testing.T, id string, coords []int64, exp string) {
+	code, err := ApgCodeEncode(coords, APG_MAX_PERIOD)
+	if err != nil {
+		t.Errorf("%s: ApgCodeEncode returned error %s", id, err.Error())
+		return
+	}
+	if code != exp {
+		t.Errorf("%s: Expected '%s' actual '%s'", id, exp, code)
+	}
+}
+
+func testApgDecodeError(t *testing.T, code, exp string) {
+	_, err := ApgCodeDecode(code)
+	if err == nil {
+		t.Errorf("ApgCodeDecode: '%s' Expected error containing '%s'", code, exp)
+		return
+	}
+	if !strings.Contains(err.Error(), exp) {
+		t.Errorf("ApgCodeDecode: '%s' Expected error containing '%s' actual '%s'", code, exp, err.Error())
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BENCH_DEFAULT_THRESHOLD = 10.0 // Percentage increase in time per generation that is reported as slower
+)
+
+// A pattern file and the number of generations to run it for.
+type BenchPattern struct {
+	File string
+	Gens int
+}
+
+// The patterns used when none are given. Each one runs for a second or so.
+var BENCH_PATTERNS = []*BenchPattern{
+	{File: "testdata/GliderGun.rle", Gens: 1000},
+	{File: "testdata/2enginecordership.rle", Gens: 500},
+	{File: "testdata/134p39.1.rle", Gens: 500},
+	{File: "testdata/1234_synth.rle", Gens: 50},
+}
+
+type BenchResult struct {
+	Name         string    `json:"name"`
+	File         string    `json:"file"`
+	Gens         int       `json:"gens"`
+	StartCells   int       `json:"startCells"`
+	EndCells     int       `json:"endCells"`
+	TotalMs      float64   `json:"totalMs"`
+	MsPerGen     float64   `json:"msPerGen"`
+	MinGenMs     float64   `json:"minGenMs"`
+	MaxGenMs     float64   `json:"maxGenMs"`
+	CellsPerSec  float64   `json:"cellsPerSec"`  // Live cells processed per second
+	AllocsPerGen float64   `json:"allocsPerGen"` // Heap allocations per generation
+	BytesPerGen  float64   `json:"bytesPerGen"`  // Heap bytes allocated per generation
+	GenMs        []float64 `json:"genMs"`        // The time taken by each generation
+}
+
+type BenchReport struct {
+	Created   string         `json:"created"`
+	GoVersion string         `json:"goVersion"`
+	GoOs      string         `json:"goOs"`
+	GoArch    string         `json:"goArch"`
+	Results   []*BenchResult `json:"results"`
+}
+
+// A result compared to the same pattern in a baseline report.
+type BenchCompare struct {
+	Name       string
+	BaseMs     float64 // Baseline time per generation
+	MsPerGen   float64 // Current time per generation
+	ChangePct  float64 // Percentage change in time per generation. Positive is slower
+	Slower     bool    // ChangePct is above the threshold
+	NoBaseline bool    // The pattern was not in the baseline
+}
+
+// Parse a comma separated list of patterns in the form file:gens.
+// If :gens is not given defaultGens is used.
+func ParseBenchPatterns(list string, defaultGens int) ([]*BenchPattern, error) {
+	resp := make([]*BenchPattern, 0)
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		p := &BenchPattern{File: v, Gens: defaultGens}
+		pos := strings.LastIndexByte(v, ':')
+		if pos > 0 {
+			n, err := strconv.Atoi(v[pos+1:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("pattern '%s' generations '%s' must be a number greater than 0", v, v[pos+1:])
+			}
+			p.File = v[:pos]
+			p.Gens = n
+		}
+		resp = append(resp, p)
+	}
+	if len(resp) == 0 {
+		return nil, fmt.Errorf("no patterns in '%s'", list)
+	}
+	return resp, nil
+}
+
+func NewBenchReport() *BenchReport {
+	return &BenchReport{Created: time.Now().Format(time.RFC3339), GoVersion: runtime.Version(), GoOs: runtime.GOOS, GoArch: runtime.GOARCH, Results: make([]*BenchResult, 0)}
+}
+
+// Load and run a single pattern, timing each generation.
+func RunBenchPattern(p *BenchPattern) (*BenchResult, error) {
+	rle, err := NewRleFile(p.File)
+	if err != nil {
+		return nil, err
+	}
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	lg.AddCellsAtOffset(0, 0, 0, rle.coords)
+	res := &BenchResult{Name: strings.TrimSuffix(filepath.Base(p.File), filepath.Ext(p.File)), File: p.File, Gens: p.Gens, StartCells: lg.CountCells(), GenMs: make([]float64, p.Gens)}
+
+	var memBefore, memAfter runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&memBefore)
+	var cells int64 = 0
+	var total time.Duration = 0
+	for i := 0; i < p.Gens; i++ {
+		cells = cells + int64(lg.GetCellCount())
+		start := time.Now()
+		lg.NextGen()
+		d := time.Since(start)
+		total = total + d
+		res.GenMs[i] = float64(d.Microseconds()) / 1000
+	}
+	runtime.ReadMemStats(&memAfter)
+
+	res.EndCells = lg.CountCells()
+	res.TotalMs = float64(total.Microseconds()) / 1000
+	if p.Gens > 0 {
+		res.MsPerGen = res.TotalMs / float64(p.Gens)
+		res.AllocsPerGen = float64(memAfter.Mallocs-memBefore.Mallocs) / float64(p.Gens)
+		res.BytesPerGen = float64(memAfter.TotalAlloc-memBefore.TotalAlloc) / float64(p.Gens)
+		res.MinGenMs = res.GenMs[0]
+		for _, ms := range res.GenMs {
+			if ms < res.MinGenMs {
+				res.MinGenMs = ms
+			}
+			if ms > res.MaxGenMs {
+				res.MaxGenMs = ms
+			}
+		}
+	}
+	if total > 0 {
+		res.CellsPerSec = float64(cells) / total.Seconds()
+	}
+	return res, nil
+}
+
+func LoadBenchReport(fileName string) (*BenchReport, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	rep := &BenchReport{}
+	if err := json.Unmarshal(b, rep); err != nil {
+		return nil, fmt.Errorf("benchmark file '%s' is invalid. %s", fileName, err.Error())
+	}
+	return rep, nil
+}
+
+func (br *BenchReport) Save(fileName string) error {
+	b, err := json.MarshalIndent(br, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, b, 0644)
+}
+
+func (br *BenchReport) Find(name string) *BenchResult {
+	for _, r := range br.Results {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Compare each result with the same pattern in the baseline.
+// A result is Slower if its time per generation has increased by more than thresholdPct percent.
+func (br *BenchReport) Compare(base *BenchReport, thresholdPct float64) []*BenchCompare {
+	resp := make([]*BenchCompare, 0)
+	for _, r := range br.Results {
+		c := &BenchCompare{Name: r.Name, MsPerGen: r.MsPerGen}
+		b := base.Find(r.Name)
+		if b == nil || b.MsPerGen <= 0 {
+			c.NoBaseline = true
+		} else {
+			c.BaseMs = b.MsPerGen
+			c.ChangePct = (r.MsPerGen - b.MsPerGen) * 100 / b.MsPerGen
+			c.Slower = c.ChangePct > thresholdPct
+		}
+		resp = append(resp, c)
+	}
+	return resp
+}
+
+func (br *BenchResult) String() string {
+	return fmt.Sprintf("%-20s gens:%5d cells:%6d->%-6d %9.3fms/gen (min %.3f max %.3f) %12.0f cells/sec %9.0f allocs/gen %11.0f bytes/gen",
+		br.Name, br.Gens, br.StartCells, br.EndCells, br.MsPerGen, br.MinGenMs, br.MaxGenMs, br.CellsPerSec, br.AllocsPerGen, br.BytesPerGen)
+}
+
+func (bc *BenchCompare) String() string {
+	if bc.NoBaseline {
+		return fmt.Sprintf("%-20s %9.3fms/gen  no baseline", bc.Name, bc.MsPerGen)
+	}
+	status := "ok"
+	if bc.Slower {
+		status = "SLOWER"
+	}
+	return fmt.Sprintf("%-20s %9.3fms/gen  baseline %9.3fms/gen  %+7.1f%%  %s", bc.Name, bc.MsPerGen, bc.BaseMs, bc.ChangePct, status)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBenchParsePatterns(t *testing.T) {
+	p, err := ParseBenchPatterns("testdata/blinker.rle:20, testdata/GliderGun.rle,", 50)
+	if err != nil {
+		t.Fatalf("parse failed. %s", err.Error())
+	}
+	if len(p) != 2 || p[0].File != "testdata/blinker.rle" || p[0].Gens != 20 || p[1].File != "testdata/GliderGun.rle" || p[1].Gens != 50 {
+		t.Errorf("parse returned the wrong patterns %v %v", p[0], p[1])
+	}
+	AssertBenchParseError(t, "", "no patterns in ''")
+	AssertBenchParseError(t, "a.rle:x", "pattern 'a.rle:x' generations 'x' must be a number greater than 0")
+	AssertBenchParseError(t, "a.rle:0", "pattern 'a.rle:0' generations '0' must be a number greater than 0")
+}
+
+func TestBenchRun(t *testing.T) {
+	res, err := RunBenchPattern(&BenchPattern{File: "testdata/blinker.rle", Gens: 10})
+	if err != nil {
+		t.Fatalf("run failed. %s", err.Error())
+	}
+	if res.Name != "blinker" || res.StartCells != 3 || res.EndCells != 3 || len(res.GenMs) != 10 {
+		t.Errorf("run returned the wrong result %s", res.String())
+	}
+	if res.MinGenMs > res.MaxGenMs || res.TotalMs < res.MaxGenMs {
+		t.Errorf("run timings are inconsistent %s", res.String())
+	}
+	_, err = RunBenchPattern(&BenchPattern{File: "testdata/missing.rle", Gens: 10})
+	if err == nil {
+		t.Errorf("run of a missing file should fail")
+	}
+}
+
+func TestBenchSaveAndCompare(t *testing.T) {
+	base := NewBenchReport()
+	base.Results = append(base.Results, &BenchResult{Name: "a", MsPerGen: 1.0}, &BenchResult{Name: "b", MsPerGen: 2.0})
+	fn := filepath.Join(t.TempDir(), "base.json")
+	if err := base.Save(fn); err != nil {
+		t.Fatalf("save failed. %s", err.Error())
+	}
+	loaded, err := LoadBenchReport(fn)
+	if err != nil {
+		t.Fatalf("load failed. %s", err.Error())
+	}
+	if len(loaded.Results) != 2 || loaded.Find("b").MsPerGen != 2.0 || loaded.Created != base.Created {
+		t.Errorf("loaded report does not match the saved report")
+	}
+
+	cur := NewBenchReport()
+	cur.Results = append(cur.Results, &BenchResult{Name: "a", MsPerGen: 1.05}, &BenchResult{Name: "b", MsPerGen: 3.0}, &BenchResult{Name: "c", MsPerGen: 1.0})
+	cmp := cur.Compare(loaded, 10)
+	if cmp[0].Slower || cmp[0].NoBaseline {
+		t.Errorf("a is 5%% slower and should be ok. %s", cmp[0].String())
+	}
+	if !cmp[1].Slower || cmp[1].ChangePct != 50 {
+		t.Errorf("b is 50%% slower and should be flagged. %s", cmp[1].String())
+	}
+	if !cmp[2].NoBaseline || !strings.HasSuffix(cmp[2].String(), "no baseline") {
+		t.Errorf("c has no baseline. %s", cmp[2].String())
+	}
+}
+
+func TestCliBench(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "bench.json")
+	stdout := AssertCliCommand(t, []string{"bench", "-patterns", "testdata/blinker.rle:5", "-out", out}, CLI_EXIT_OK)
+	if !strings.HasPrefix(stdout, "blinker") {
+		t.Errorf("bench output should start with the pattern name. Output:\n%s", stdout)
+	}
+	rep, err := LoadBenchReport(out)
+	if err != nil {
+		t.Fatalf("bench output did not load. %s", err.Error())
+	}
+	// Make the baseline impossibly fast so the current run is slower
+	rep.Results[0].MsPerGen = 0.0000001
+	base := filepath.Join(dir, "base.json")
+	rep.Save(base)
+	AssertCliCommand(t, []string{"bench", "-patterns", "testdata/blinker.rle:5", "-baseline", base}, CLI_EXIT_SLOW)
+	AssertCliCommand(t, []string{"bench", "-patterns", "testdata/blinker.rle:5", "-baseline", filepath.Join(dir, "missing.json")}, CLI_EXIT_ERROR)
+	AssertCliCommand(t, []string{"bench", "-patterns", "testdata/blinker.rle:x"}, CLI_EXIT_USAGE)
+}
+
+func AssertBenchParseError(t *testing.T, list, exp string) {
+	_, err := ParseBenchPatterns(list, 10)
+	if err == nil {
+		t.Errorf("parse '%s' should have failed with '%s'", list, exp)
+		return
+	}
+	if err.Error() != exp {
+		t.Errorf("parse '%s' error '%s' expected '%s'", list, err.Error(), exp)
+	}
+}
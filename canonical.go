@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+/*
+Find the canonical form of a set of cells so the same object in any position or orientation gives the same cells.
+
+	Each of the 8 symmetries is applied and the cells normalised (see POCNormaliseCoords) and sorted by y then x.
+	The canonical form is the smallest of these lists when compared cell by cell.
+
+Unlike ApgCanonicalWechsler the cost does not depend on the size of the bounding box so it can be used for sparse patterns.
+Returns the sorted cells and the symmetry that was applied to get them.
+*/
+func CanonicalCoords(coords []int64) ([]int64, LifeSymmetry) {
+	var best []int64
+	bestSym := SYM_NONE
+	for s := SYM_NONE; s < SYM_COUNT; s++ {
+		co, _, _ := TransformCoords(coords, s)
+		canonicalSort(co)
+		if best == nil || canonicalLess(co, best) {
+			best = co
+			bestSym = s
+		}
+	}
+	return best, bestSym
+}
+
+/*
+A 64 bit FNV-1a hash of the canonical form of the cells.
+Equal hashes mean the cells are (almost certainly) the same object in a different position or orientation.
+*/
+func CanonicalHash(coords []int64) uint64 {
+	co, _ := CanonicalCoords(coords)
+	return canonicalHashSorted(co)
+}
+
+// Hash cells that are already in canonical order
+func canonicalHashSorted(co []int64) uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, v := range co {
+		binary.LittleEndian.PutUint64(buf, uint64(v))
+		h.Write(buf)
+	}
+	return h.Sum64()
+}
+
+// The hash as 16 hex digits. Used in the index and on the command line.
+func CanonicalHashString(h uint64) string {
+	return fmt.Sprintf("%016x", h)
+}
+
+// Sort x,y pairs in place by y then x
+func canonicalSort(co []int64) {
+	sort.Sort(canonicalPairs(co))
+}
+
+type canonicalPairs []int64
+
+func (p canonicalPairs) Len() int {
+	return len(p) / 2
+}
+
+func (p canonicalPairs) Less(i, j int) bool {
+	if p[i*2+1] != p[j*2+1] {
+		return p[i*2+1] < p[j*2+1]
+	}
+	return p[i*2] < p[j*2]
+}
+
+func (p canonicalPairs) Swap(i, j int) {
+	p[i*2], p[j*2] = p[j*2], p[i*2]
+	p[i*2+1], p[j*2+1] = p[j*2+1], p[i*2+1]
+}
+
+// Both lists are the same length. Compare y then x for each cell
+func canonicalLess(a, b []int64) bool {
+	for i := 0; i < len(a); i = i + 2 {
+		if a[i+1] != b[i+1] {
+			return a[i+1] < b[i+1]
+		}
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+// The canonical form of the cells in the file
+func (rle *RLE) Canonical() []int64 {
+	co, _ := CanonicalCoords(rle.coords)
+	return co
+}
+
+// The canonical hash of the cells in the file
+func (rle *RLE) Hash() uint64 {
+	return CanonicalHash(rle.coords)
+}
+
+// The canonical form of the current generation
+func (lg *LifeGen) Canonical() []int64 {
+	co, _ := CanonicalCoords(lg.ListCellsWithMode(0))
+	return co
+}
+
+// The canonical hash of the current generation. Can be used to detect a pattern that repeats in any orientation
+func (lg *LifeGen) Hash() uint64 {
+	return CanonicalHash(lg.ListCellsWithMode(0))
+}
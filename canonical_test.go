@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalCoords(t *testing.T) {
+	glider := []int64{1, 0, 2, 1, 0, 2, 1, 2, 2, 2}
+	exp, _ := CanonicalCoords(glider)
+	if fmt.Sprint(exp) != "[0 0 1 0 2 0 0 1 1 2]" {
+		t.Errorf("glider canonical form is wrong %v", exp)
+	}
+	hash := CanonicalHash(glider)
+	for s := SYM_NONE; s < SYM_COUNT; s++ {
+		moved := compareApply(glider, s, -7, 100)
+		co, sym := CanonicalCoords(moved)
+		if fmt.Sprint(co) != fmt.Sprint(exp) {
+			t.Errorf("%s: canonical form %v expected %v", s.String(), co, exp)
+		}
+		back, _, _ := TransformCoords(moved, sym)
+		if !apgEqual(back, co) {
+			t.Errorf("%s: the returned symmetry %s does not give the canonical form", s.String(), sym.String())
+		}
+		if CanonicalHash(moved) != hash {
+			t.Errorf("%s: hash is different", s.String())
+		}
+	}
+	// The order of the cells is not important
+	if CanonicalHash([]int64{2, 2, 1, 2, 0, 2, 2, 1, 1, 0}) != hash {
+		t.Errorf("hash depends on the order of the cells")
+	}
+	if CanonicalHash([]int64{0, 0, 1, 0, 2, 0}) == hash || CanonicalHash([]int64{0, 0, 1, 0, 2, 0}) == CanonicalHash([]int64{0, 0, 1, 1, 2, 2}) {
+		t.Errorf("different patterns have the same hash")
+	}
+	if CanonicalHashString(0xab) != "00000000000000ab" {
+		t.Errorf("hash string is wrong %s", CanonicalHashString(0xab))
+	}
+
+	// A glider has the same hash every 2 generations (it is reflected) and the same as the RLE
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	lg.AddCellsAtOffset(10, 10, 0, glider)
+	lg.NextGen()
+	if lg.Hash() == hash {
+		t.Errorf("glider after 1 generation should have a different hash")
+	}
+	lg.NextGen()
+	if lg.Hash() != hash || fmt.Sprint(lg.Canonical()) != fmt.Sprint(exp) {
+		t.Errorf("glider after 2 generations should have the same hash")
+	}
+	rle, _ := NewRleReader(strings.NewReader("x = 3, y = 3\n3o$2bo$bo!"), "test")
+	if rle.Hash() != hash || fmt.Sprint(rle.Canonical()) != fmt.Sprint(exp) {
+		t.Errorf("RLE glider should have the same hash")
+	}
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	CLI_EXIT_OK    = 0 // The command completed
+	CLI_EXIT_ERROR = 1 // A file could not be loaded or saved
+	CLI_EXIT_USAGE = 2 // The command line was invalid
+	CLI_EXIT_SLOW  = 3 // A benchmark was slower than its baseline
+	CLI_EXIT_DIFF  = 4 // Compared patterns are not the same
+)
+
+// A sub command that runs without opening a window.
+// args does not include the command name. Returns the process exit code.
+type CliCommand struct {
+	name  string
+	usage string
+	run   func(args []string, stdout, stderr io.Writer) int
+}
+
+var cliCommands = []*CliCommand{
+	{name: "run", usage: "Load a pattern, run it for a number of generations and optionally save the result", run: CliRun},
+	{name: "bench", usage: "Time a set of patterns, save the results and compare them with a baseline", run: CliBench},
+	{name: "convert", usage: "Convert a pattern file, or a directory of them, between rle, cells and life106 formats", run: CliConvert},
+	{name: "script", usage: "Run a script of load, place, step, select, save and assert commands", run: CliScript},
+	{name: "compare", usage: "Check if two pattern files hold the same object, allowing rotation, reflection, translation and phase", run: CliCompare},
+	{name: "index", usage: "Index the pattern files in a directory tree so they can be searched", run: CliIndex},
+	{name: "search", usage: "Search a pattern index by keyword, rule, size, population or period", run: CliSearch},
+	{name: "serve", usage: "Run an HTTP JSON API for remote controlled Life sessions", run: CliServe},
+}
+
+// Find and run the sub command named in args[0].
+func RunCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		cliUsage(stderr)
+		return CLI_EXIT_USAGE
+	}
+	for _, c := range cliCommands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "Unknown command '%s'\n", args[0])
+	cliUsage(stderr)
+	return CLI_EXIT_USAGE
+}
+
+func cliUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: grtest [app|command] [options]")
+	fmt.Fprintln(w, "Apps (open a window. With no app a chooser is shown):")
+	for _, a := range launchApps {
+		fmt.Fprintf(w, "  %-8s %s\n", a.name, a.title)
+	}
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range cliCommands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintln(w, "Use 'grtest <app|command> -h' for the options")
+}
+
+// Create a FlagSet for a command that writes errors to stderr and does not exit.
+func cliFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+/*
+grtest run -in pattern.rle -gens 1000 -out result.rle -rule B36/S23
+*/
+func CliRun(args []string, stdout, stderr io.Writer) int {
+	fs := cliFlagSet("run", stderr)
+	in := fs.String("in", "", "The RLE file to load (required)")
+	gens := fs.Int("gens", 100, "The number of generations to run")
+	out := fs.String("out", "", "The RLE file to save the final generation to")
+	owner := fs.String("owner", "", "The owner written to the saved file")
+	comment := fs.String("comment", "", "The comment written to the saved file")
+	ruleName := fs.String("rule", "", "The rule in B/S notation. Default is the rule in the file")
+	if err := fs.Parse(args); err != nil {
+		return CLI_EXIT_USAGE
+	}
+	if *in == "" {
+		fmt.Fprintln(stderr, "run: -in <file> is required")
+		fs.Usage()
+		return CLI_EXIT_USAGE
+	}
+	if *gens < 0 {
+		fmt.Fprintf(stderr, "run: -gens %d must not be negative\n", *gens)
+		return CLI_EXIT_USAGE
+	}
+
+	rle, err := NewRleFile(*in)
+	if err != nil {
+		fmt.Fprintf(stderr, "run: %s\n", err.Error())
+		return CLI_EXIT_ERROR
+	}
+	if *ruleName == "" {
+		*ruleName = rle.rule
+	}
+	rule, err := ParseLifeRule(*ruleName)
+	if err != nil {
+		fmt.Fprintf(stderr, "run: %s\n", err.Error())
+		return CLI_EXIT_ERROR
+	}
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	lg.SetRule(rule)
+	lg.AddCellsAtOffset(0, 0, 0, rle.coords)
+
+	start := time.Now()
+	for i := 0; i < *gens; i++ {
+		lg.NextGen()
+	}
+	elapsed := time.Since(start)
+
+	fmt.Fprintf(stdout, "File       : %s\n", *in)
+	fmt.Fprintf(stdout, "Rule       : %s\n", rule.String())
+	fmt.Fprintf(stdout, "Generations: %d\n", lg.GetGenerationCount())
+	fmt.Fprintf(stdout, "Population : %d\n", lg.CountCells())
+	fmt.Fprintf(stdout, "Bounds     : %s\n", cliBounds(lg))
+	fmt.Fprintf(stdout, "Time       : %dms%s\n", elapsed.Milliseconds(), cliRate(*gens, elapsed))
+
+	if *out != "" {
+		save := NewRLESave(*out, lg.ListCellsWithMode(0), *owner, *comment)
+		save.rule = rule.String()
+		if err := save.Save(); err != nil {
+			fmt.Fprintf(stderr, "run: %s\n", err.Error())
+			return CLI_EXIT_ERROR
+		}
+		fmt.Fprintf(stdout, "Saved      : %s\n", save.fileName)
+	}
+	return CLI_EXIT_OK
+}
+
+/*
+grtest bench -patterns testdata/GliderGun.rle:1000 -out bench.json -baseline base.json -threshold 10
+*/
+func CliBench(args []string, stdout, stderr io.Writer) int {
+	fs := cliFlagSet("bench", stderr)
+	list := fs.String("patterns", "", "Comma separated list of file:gens. Default is the built in set")
+	gens := fs.Int("gens", 100, "Generations for patterns given without :gens")
+	out := fs.String("out", "", "The JSON file to save the results to")
+	baseline := fs.String("baseline", "", "A JSON file from a previous run to compare the results with")
+	threshold := fs.Float64("threshold", BENCH_DEFAULT_THRESHOLD, "Percentage increase in time per generation reported as slower")
+	if err := fs.Parse(args); err != nil {
+		return CLI_EXIT_USAGE
+	}
+	patterns := BENCH_PATTERNS
+	if *list != "" {
+		p, err := ParseBenchPatterns(*list, *gens)
+		if err != nil {
+			fmt.Fprintf(stderr, "bench: %s\n", err.Error())
+			return CLI_EXIT_USAGE
+		}
+		patterns = p
+	}
+	var base *BenchReport
+	if *baseline != "" {
+		b, err := LoadBenchReport(*baseline)
+		if err != nil {
+			fmt.Fprintf(stderr, "bench: %s\n", err.Error())
+			return CLI_EXIT_ERROR
+		}
+		base = b
+	}
+
+	report := NewBenchReport()
+	for _, p := range patterns {
+		res, err := RunBenchPattern(p)
+		if err != nil {
+			fmt.Fprintf(stderr, "bench: %s\n", err.Error())
+			return CLI_EXIT_ERROR
+		}
+		report.Results = append(report.Results, res)
+		fmt.Fprintln(stdout, res.String())
+	}
+	if *out != "" {
+		if err := report.Save(*out); err != nil {
+			fmt.Fprintf(stderr, "bench: %s\n", err.Error())
+			return CLI_EXIT_ERROR
+		}
+		fmt.Fprintf(stdout, "Saved: %s\n", *out)
+	}
+	if base == nil {
+		return CLI_EXIT_OK
+	}
+	fmt.Fprintf(stdout, "Compared with %s (created %s) threshold %.1f%%\n", *baseline, base.Created, *threshold)
+	slower := 0
+	for _, c := range report.Compare(base, *threshold) {
+		fmt.Fprintln(stdout, c.String())
+		if c.Slower {
+			slower++
+		}
+	}
+	if slower > 0 {
+		fmt.Fprintf(stdout, "%d pattern(s) slower than the baseline\n", slower)
+		return CLI_EXIT_SLOW
+	}
+	return CLI_EXIT_OK
+}
+
+/*
+grtest convert -in pattern.rle -out pattern.cells
+grtest convert -in patterns -out converted -format life106 -canonical
+*/
+func CliConvert(args []string, stdout, stderr io.Writer) int {
+	fs := cliFlagSet("convert", stderr)
+	in := fs.String("in", "", "The pattern file, or directory tree, to convert (required)")
+	out := fs.String("out", "", "The file or directory to write (required)")
+	format := fs.String("format", "", "The format to write. Default is from the -out extension, or rle for a directory")
+	opts := &ConvertOptions{}
+	fs.BoolVar(&opts.Normalise, "normalise", false, "Move the cells so the top left is 0,0")
+	fs.BoolVar(&opts.Canonical, "canonical", false, "Rotate, flip and move to the canonical form (as saved by the GUI)")
+	fs.StringVar(&opts.Name, "name", "", "Replace the name")
+	fs.StringVar(&opts.Owner, "owner", "", "Replace the owner")
+	fs.StringVar(&opts.Comment, "comment", "", "Replace the comment")
+	if err := fs.Parse(args); err != nil {
+		return CLI_EXIT_USAGE
+	}
+	if *in == "" || *out == "" {
+		fmt.Fprintln(stderr, "convert: -in and -out are required")
+		fs.Usage()
+		return CLI_EXIT_USAGE
+	}
+	info, err := os.Stat(*in)
+	if err != nil {
+		fmt.Fprintf(stderr, "convert: %s\n", err.Error())
+		return CLI_EXIT_ERROR
+	}
+	var formatErr error
+	switch {
+	case *format != "":
+		opts.Format, formatErr = ParsePatternFormat(*format)
+	case info.IsDir():
+		opts.Format = PATTERN_RLE
+	default:
+		opts.Format, formatErr = PatternFormatForFile(*out)
+	}
+	if formatErr != nil {
+		fmt.Fprintf(stderr, "convert: %s\n", formatErr.Error())
+		return CLI_EXIT_USAGE
+	}
+
+	if !info.IsDir() {
+		if err := ConvertPattern(*in, *out, opts); err != nil {
+			fmt.Fprintf(stderr, "convert: %s\n", err.Error())
+			return CLI_EXIT_ERROR
+		}
+		fmt.Fprintf(stdout, "%s -> %s\n", *in, *out)
+		return CLI_EXIT_OK
+	}
+	ok, failed, err := ConvertDir(*in, *out, opts, func(inFile, outFile string, err error) {
+		if err != nil {
+			fmt.Fprintf(stderr, "convert: %s\n", err.Error())
+		} else {
+			fmt.Fprintf(stdout, "%s -> %s\n", inFile, outFile)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "convert: %s\n", err.Error())
+		return CLI_EXIT_ERROR
+	}
+	fmt.Fprintf(stdout, "Converted %d file(s). %d failed\n", ok, failed)
+	if failed > 0 {
+		return CLI_EXIT_ERROR
+	}
+	return CLI_EXIT_OK
+}
+
+/*
+grtest serve -addr localhost:8080
+*/
+func CliServe(args []string, stdout, stderr io.Writer) int {
+	fs := cliFlagSet("serve", stderr)
+	addr := fs.String("addr", SERVER_DEFAULT_ADDR, "The address to listen on. Use localhost to only allow this machine")
+	if err := fs.Parse(args); err != nil {
+		return CLI_EXIT_USAGE
+	}
+	server := &http.Server{Addr: *addr, Handler: NewLifeServer().Handler(), ReadHeaderTimeout: 10 * time.Second}
+	fmt.Fprintf(stdout, "Listening on http://%s/sessions\n", *addr)
+	fmt.Fprintf(stdout, "Viewer at   http://%s/viewer?session=<name>\n", *addr)
+	if err := server.ListenAndServe(); err != nil {
+		fmt.Fprintf(stderr, "serve: %s\n", err.Error())
+		return CLI_EXIT_ERROR
+	}
+	return CLI_EXIT_OK
+}
+
+/*
+grtest script -in build.script -rule B36/S23
+*/
+func CliScript(args []string, stdout, stderr io.Writer) int {
+	fs := cliFlagSet("script", stderr)
+	in := fs.String("in", "", "The script file to run (required)")
+	ruleName := fs.String("rule", LIFE_RULE_CONWAY, "The rule in B/S notation before the script changes it")
+	quiet := fs.Bool("q", false, "Do not list each command as it runs")
+	if err := fs.Parse(args); err != nil {
+		return CLI_EXIT_USAGE
+	}
+	if *in == "" {
+		fmt.Fprintln(stderr, "script: -in <file> is required")
+		fs.Usage()
+		return CLI_EXIT_USAGE
+	}
+	rule, err := ParseLifeRule(*ruleName)
+	if err != nil {
+		fmt.Fprintf(stderr, "script: %s\n", err.Error())
+		return CLI_EXIT_USAGE
+	}
+	lg := NewLifeGen(nil, 0)
+	lg.SetRule(rule)
+	var log io.Writer = stdout
+	if *quiet {
+		log = nil
+	}
+	if err := NewLifeScript(*in, lg, log).RunFile(); err != nil {
+		fmt.Fprintf(stderr, "script: %s\n", err.Error())
+		return CLI_EXIT_ERROR
+	}
+	return CLI_EXIT_OK
+}
+
+/*
+grtest compare -a glider.rle -b other/glider.cells -period 4
+*/
+func CliCompare(args []string, stdout, stderr io.Writer) int {
+	fs := cliFlagSet("compare", stderr)
+	a := fs.String("a", "", "The first pattern file (required)")
+	b := fs.String("b", "", "The second pattern file (required)")
+	period := fs.Int("period", 1, "Run the first pattern for up to period-1 generations to find a matching phase")
+	ruleName := fs.String("rule", "", "The rule in B/S notation used to find the phase. Default is the rule in the first file")
+	if err := fs.Parse(args); err != nil {
+		return CLI_EXIT_USAGE
+	}
+	if *a == "" || *b == "" || *period < 1 {
+		fmt.Fprintln(stderr, "compare: -a and -b are required and -period must be at least 1")
+		fs.Usage()
+		return CLI_EXIT_USAGE
+	}
+	rleA, err := LoadPattern(*a)
+	if err != nil {
+		fmt.Fprintf(stderr, "compare: %s\n", err.Error())
+		return CLI_EXIT_ERROR
+	}
+	rleB, err := LoadPattern(*b)
+	if err != nil {
+		fmt.Fprintf(stderr, "compare: %s\n", err.Error())
+		return CLI_EXIT_ERROR
+	}
+	if *ruleName == "" {
+		*ruleName = rleA.rule
+	}
+	rule, err := ParseLifeRule(*ruleName)
+	if err != nil {
+		fmt.Fprintf(stderr, "compare: %s\n", err.Error())
+		return CLI_EXIT_ERROR
+	}
+	m := ComparePatterns(rleA.coords, rleB.coords, *period, rule)
+	if m == nil {
+		fmt.Fprintf(stdout, "Different: %s (%d cells) and %s (%d cells)\n", *a, len(rleA.coords)/2, *b, len(rleB.coords)/2)
+		return CLI_EXIT_DIFF
+	}
+	fmt.Fprintf(stdout, "Same: %s -> %s: %s\n", *a, *b, m.String())
+	return CLI_EXIT_OK
+}
+
+/*
+grtest index -dir patterns -dups
+*/
+func CliIndex(args []string, stdout, stderr io.Writer) int {
+	fs := cliFlagSet("index", stderr)
+	dir := fs.String("dir", "", "The directory tree to index (required)")
+	indexFile := fs.String("index", "", "The index file. Default is "+INDEX_FILE_NAME+" in -dir")
+	gens := fs.Int("gens", INDEX_PERIOD_GENS, "The maximum number of generations run to find the period")
+	dups := fs.Bool("dups", false, "List the files that hold the same object in any position or orientation")
+	if err := fs.Parse(args); err != nil {
+		return CLI_EXIT_USAGE
+	}
+	if *dir == "" || *gens < 0 {
+		fmt.Fprintln(stderr, "index: -dir is required and -gens must not be negative")
+		fs.Usage()
+		return CLI_EXIT_USAGE
+	}
+	pi, err := LoadPatternIndex(*dir, *indexFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "index: %s\n", err.Error())
+		return CLI_EXIT_ERROR
+	}
+	if pi.PeriodGens != *gens {
+		pi = NewPatternIndex(*dir, *indexFile) // Periods may change so index every file again
+		pi.PeriodGens = *gens
+	}
+	changes, err := pi.Update()
+	if err == nil {
+		err = pi.Save()
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "index: %s\n", err.Error())
+		return CLI_EXIT_ERROR
+	}
+	fmt.Fprintf(stdout, "%s: %d added, %d updated, %d removed, %d unchanged\n", pi.FileName(), changes.Added, changes.Updated, changes.Removed, changes.Unchanged)
+	if *dups {
+		for _, group := range pi.Duplicates() {
+			fmt.Fprintf(stdout, "Same hash %s:\n", group[0].Hash)
+			for _, e := range group {
+				fmt.Fprintf(stdout, "  %s\n", e.Path)
+			}
+		}
+	}
+	return CLI_EXIT_OK
+}
+
+/*
+grtest search -dir patterns glider kind:spaceship size<10
+*/
+func CliSearch(args []string, stdout, stderr io.Writer) int {
+	fs := cliFlagSet("search", stderr)
+	dir := fs.String("dir", "", "The directory tree that was indexed (required)")
+	indexFile := fs.String("index", "", "The index file. Default is "+INDEX_FILE_NAME+" in -dir")
+	update := fs.Bool("update", true, "Update the index before searching")
+	if err := fs.Parse(args); err != nil {
+		return CLI_EXIT_USAGE
+	}
+	if *dir == "" {
+		fmt.Fprintln(stderr, "search: -dir is required")
+		fs.Usage()
+		return CLI_EXIT_USAGE
+	}
+	q, err := ParsePatternQuery(strings.Join(fs.Args(), " "))
+	if err != nil {
+		fmt.Fprintf(stderr, "search: %s\n", err.Error())
+		return CLI_EXIT_USAGE
+	}
+	pi, err := LoadPatternIndex(*dir, *indexFile)
+	if err == nil && *update {
+		if _, err = pi.Update(); err == nil {
+			err = pi.Save()
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "search: %s\n", err.Error())
+		return CLI_EXIT_ERROR
+	}
+	found := pi.Search(q)
+	for _, e := range found {
+		fmt.Fprintln(stdout, e.String())
+	}
+	fmt.Fprintf(stdout, "Found %d of %d pattern(s)\n", len(found), len(pi.Entries))
+	return CLI_EXIT_OK
+}
+
+// The bounds of the cells as 'x1,y1 x2,y2 (w x h)'
+func cliBounds(lg *LifeGen) string {
+	if lg.GetRootCell() == nil {
+		return "empty"
+	}
+	x1, y1, x2, y2 := lg.GetBounds()
+	return fmt.Sprintf("%d,%d %d,%d (%d x %d)", x1, y1, x2, y2, x2-x1+1, y2-y1+1)
+}
+
+func cliRate(gens int, elapsed time.Duration) string {
+	if gens == 0 || elapsed <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%.1f gens/sec)", float64(gens)/elapsed.Seconds())
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCliRun(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "blinker.rle")
+	stdout := AssertCliCommand(t, []string{"run", "-in", "testdata/blinker.rle", "-gens", "3", "-out", out}, CLI_EXIT_OK)
+	for _, exp := range []string{"Generations: 3\n", "Population : 3\n", "Bounds     : 1,-1 1,1 (1 x 3)\n", "Saved      : " + out} {
+		if !strings.Contains(stdout, exp) {
+			t.Errorf("run output should contain '%s'. Output:\n%s", exp, stdout)
+		}
+	}
+	rle, err := NewRleFile(out)
+	if err != nil {
+		t.Fatalf("saved file did not load. %s", err.Error())
+	}
+	enc, w, h := rle.Encode()
+	if enc != "o$o$o!" || w != 1 || h != 3 {
+		t.Errorf("saved blinker should be vertical. Got '%s' %dx%d", enc, w, h)
+	}
+}
+
+func TestCliRunErrors(t *testing.T) {
+	AssertCliCommand(t, []string{}, CLI_EXIT_USAGE)
+	AssertCliCommand(t, []string{"walk"}, CLI_EXIT_USAGE)
+	AssertCliCommand(t, []string{"run"}, CLI_EXIT_USAGE)
+	AssertCliCommand(t, []string{"run", "-in", "testdata/blinker.rle", "-gens", "-1"}, CLI_EXIT_USAGE)
+	AssertCliCommand(t, []string{"run", "-in", "testdata/blinker.rle", "-bad"}, CLI_EXIT_USAGE)
+	AssertCliCommand(t, []string{"run", "-in", "testdata/missing.rle"}, CLI_EXIT_ERROR)
+}
+
+func AssertCliCommand(t *testing.T, args []string, exp int) string {
+	var stdout, stderr bytes.Buffer
+	code := RunCommand(args, &stdout, &stderr)
+	if code != exp {
+		t.Errorf("command %v returned %d expected %d. Stderr:\n%s", args, code, exp, stderr.String())
+	}
+	return stdout.String()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+The text put on the clipboard for a set of cells. A header line and the RLE encoded cells.
+The cells are moved so the top left is 0,0. This is the same as Golly copies so it can be pasted in to Golly and LifeWiki.
+*/
+func ClipboardEncode(coords []int64, rule string) string {
+	norm, _, _ := POCNormaliseCoords(coords)
+	enc, w, h := RLEEncodeCoords(norm)
+	if rule == "" {
+		rule = RLE_DEFAULT_RULE
+	}
+	return fmt.Sprintf("x = %d, y = %d, rule = %s\n%s\n", w, h, rule, RLEWrapLines(enc, RLE_LINE_LENGTH))
+}
+
+/*
+Read cells from clipboard text. RLE (as copied from Golly or LifeWiki), Life 1.06 and Plaintext are accepted.
+Text before an RLE header line (for example the rest of a web page) is ignored.
+The cells are moved so the top left is 0,0.
+*/
+func ClipboardDecode(text string) (*RLE, error) {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil, fmt.Errorf("the clipboard is empty")
+	}
+	format := PATTERN_RLE
+	switch {
+	case strings.HasPrefix(text, "#Life 1.06"):
+		format = PATTERN_LIFE_106
+	case clipboardIsPlaintext(text):
+		format = PATTERN_PLAINTEXT
+	default:
+		text = clipboardRLEStart(text)
+	}
+	rle, err := NewPatternReader(strings.NewReader(text), "clipboard", format)
+	if err != nil {
+		return nil, err
+	}
+	if len(rle.coords) == 0 {
+		return nil, fmt.Errorf("the clipboard does not contain any cells")
+	}
+	norm, _, _ := POCNormaliseCoords(rle.coords)
+	rle.SetCoords(norm)
+	return rle, nil
+}
+
+// Plaintext has lines of '.', 'O' and '*' and comment lines starting with '!'
+func clipboardIsPlaintext(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		if strings.Trim(line, ".O*") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Drop lines before the comments and header of an RLE pattern. Space around lines (from indented web pages) is removed
+func clipboardRLEStart(text string) string {
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	for i, line := range lines {
+		if strings.HasPrefix(line, "x") && strings.Contains(line, "=") {
+			start := i
+			for start > 0 && strings.HasPrefix(lines[start-1], "#") {
+				start--
+			}
+			return strings.Join(lines[start:], "\n")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClipboardEncode(t *testing.T) {
+	// The glider is moved to 0,0
+	glider := []int64{11, 10, 12, 11, 10, 12, 11, 12, 12, 12}
+	text := ClipboardEncode(glider, "B36/S23")
+	if text != "x = 3, y = 3, rule = B36/S23\nbo$2bo$3o!\n" {
+		t.Errorf("encoded text is wrong:\n%s", text)
+	}
+	rle, err := ClipboardDecode(text)
+	if err != nil {
+		t.Fatalf("decode failed %s", err.Error())
+	}
+	if rle.encoded != "bo$2bo$3o!" || rle.rule != "B36/S23" {
+		t.Errorf("decoded cells '%s' rule '%s' expected 'bo$2bo$3o!' 'B36/S23'", rle.encoded, rle.rule)
+	}
+}
+
+func TestClipboardDecode(t *testing.T) {
+	for desc, text := range map[string]string{
+		"golly":     "x = 3, y = 3, rule = B3/S23\r\nbo$2bo$3o!\r\n",
+		"lifewiki":  "Glider\n  #N Glider\n  #O Richard K. Guy\n  x = 3, y = 3, rule = B3/S23\n  bo$2bo$3o!\n",
+		"plaintext": testGliderCells,
+		"life106":   "#Life 1.06\n11 10\n12 11\n10 12\n11 12\n12 12\n",
+	} {
+		rle, err := ClipboardDecode(text)
+		if err != nil {
+			t.Errorf("%s: decode failed %s", desc, err.Error())
+			continue
+		}
+		if rle.encoded != "bo$2bo$3o!" {
+			t.Errorf("%s: decoded cells '%s' expected 'bo$2bo$3o!'", desc, rle.encoded)
+		}
+	}
+	for text, msg := range map[string]string{
+		"  ":              "the clipboard is empty",
+		"hello world":     "unexpected character 'h'",
+		"x = 0, y = 0\n!": "does not contain any cells",
+	} {
+		_, err := ClipboardDecode(text)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("decode of '%s' returned %v expected '%s'", text, err, msg)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import "fmt"
+
+// How pattern A maps on to pattern B.
+//
+//	A is run for Phase generations, each cell is moved by Symmetry.Apply and then by Dx, Dy.
+//	The result is exactly the cells of B.
+type PatternMatch struct {
+	Symmetry LifeSymmetry
+	Phase    int
+	Dx, Dy   int64
+}
+
+/*
+Find out if two patterns are the same object, allowing any of the 8 rotations and reflections and any translation.
+If period is greater than 1, A is also run for up to period-1 generations under rule to find a phase that matches B.
+Both patterns are normalised (see POCNormaliseCoords) and B is compared with each orientation of A.
+Returns nil if no transform maps A on to B.
+*/
+func ComparePatterns(a, b []int64, period int, rule *LifeRule) *PatternMatch {
+	if len(b) == 0 {
+		if len(a) == 0 {
+			return &PatternMatch{Symmetry: SYM_NONE}
+		}
+		return nil
+	}
+	bNorm, bw, bh := POCNormaliseCoords(b)
+	bx, by := apgMinXY(b)
+	var lg *LifeGen
+	cells := a
+	for phase := 0; phase < period || phase == 0; phase++ {
+		if phase > 0 {
+			if lg == nil {
+				lg = NewLifeGen(nil, RUN_FOR_EVER)
+				lg.SetRule(rule)
+				lg.AddCellsAtOffset(0, 0, 0, a)
+			}
+			lg.NextGen()
+			cells = lg.ListCellsWithMode(0)
+		}
+		if len(cells) == 0 {
+			return nil
+		}
+		if len(cells) != len(b) {
+			continue
+		}
+		for s := SYM_NONE; s < SYM_COUNT; s++ {
+			moved := compareApply(cells, s, 0, 0)
+			norm, w, h := POCNormaliseCoords(moved)
+			if w != bw || h != bh || !apgEqual(norm, bNorm) {
+				continue
+			}
+			mx, my := apgMinXY(moved)
+			return &PatternMatch{Symmetry: s, Phase: phase, Dx: bx - mx, Dy: by - my}
+		}
+	}
+	return nil
+}
+
+// Apply the symmetry and translation to A's cells at the matched phase
+func (m *PatternMatch) Apply(coords []int64) []int64 {
+	return compareApply(coords, m.Symmetry, m.Dx, m.Dy)
+}
+
+func (m *PatternMatch) String() string {
+	return fmt.Sprintf("%s then move %d,%d after %d generation(s)", m.Symmetry.String(), m.Dx, m.Dy, m.Phase)
+}
+
+func compareApply(coords []int64, s LifeSymmetry, dx, dy int64) []int64 {
+	out := make([]int64, len(coords))
+	for i := 0; i < len(coords); i = i + 2 {
+		x, y := s.Apply(coords[i], coords[i+1])
+		out[i], out[i+1] = x+dx, y+dy
+	}
+	return out
+}
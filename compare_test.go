@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestComparePatterns(t *testing.T) {
+	glider := []int64{1, 0, 2, 1, 0, 2, 1, 2, 2, 2}
+	conway := NewConwayRule()
+
+	// The same cells moved
+	AssertCompare(t, "moved", glider, compareApply(glider, SYM_NONE, 10, -5), 1, "none then move 10,-5 after 0 generation(s)")
+	// Every orientation is found. The match maps A on to B
+	for s := SYM_NONE; s < SYM_COUNT; s++ {
+		b := compareApply(glider, s, 3, 7)
+		m := ComparePatterns(glider, b, 1, conway)
+		if m == nil || !apgEqual(m.Apply(glider), b) {
+			t.Errorf("%s: glider was not matched. %v", s.String(), m)
+		}
+	}
+	// A glider 1 generation on and rotated is only found when phases are searched
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	lg.AddCellsAtOffset(0, 0, 0, glider)
+	lg.NextGen()
+	b := compareApply(lg.ListCellsWithMode(0), SYM_ROT_90, 0, 0)
+	AssertCompare(t, "phase 1", glider, b, 1, "")
+	AssertCompare(t, "phase 4", glider, b, 4, "rot90 then move 0,0 after 1 generation(s)")
+
+	// Blinker is the same vertical or horizontal and in either phase
+	AssertCompare(t, "blinker", []int64{0, 0, 1, 0, 2, 0}, []int64{5, 5, 5, 6, 5, 7}, 1, "rot90 then move 5,5 after 0 generation(s)")
+	// Same population and size but a different shape
+	AssertCompare(t, "different", []int64{0, 0, 1, 1}, []int64{0, 0, 1, 0}, 2, "")
+	AssertCompare(t, "empty", []int64{}, []int64{}, 1, "none then move 0,0 after 0 generation(s)")
+	AssertCompare(t, "dies", []int64{0, 0}, []int64{0, 0, 1, 0}, 3, "")
+}
+
+func TestCliCompare(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "glider.cells"), []byte(testGliderCells), 0644)
+	os.WriteFile(filepath.Join(dir, "flipped.rle"), []byte("x = 3, y = 3\n3o$o$bo!"), 0644)
+	os.WriteFile(filepath.Join(dir, "blinker.rle"), []byte("x = 3, y = 1\n3o!"), 0644)
+	out := AssertCliCommand(t, []string{"compare", "-a", filepath.Join(dir, "glider.cells"), "-b", filepath.Join(dir, "flipped.rle")}, CLI_EXIT_OK)
+	if !strings.Contains(out, "Same: ") || !strings.HasSuffix(out, ": rot180 then move 2,2 after 0 generation(s)\n") {
+		t.Errorf("compare output is wrong:\n%s", out)
+	}
+	out = AssertCliCommand(t, []string{"compare", "-a", filepath.Join(dir, "glider.cells"), "-b", filepath.Join(dir, "blinker.rle"), "-period", "4"}, CLI_EXIT_DIFF)
+	if !strings.HasPrefix(out, "Different: ") || !strings.HasSuffix(out, "blinker.rle (3 cells)\n") {
+		t.Errorf("compare output is wrong:\n%s", out)
+	}
+	AssertCliCommand(t, []string{"compare", "-a", filepath.Join(dir, "glider.cells")}, CLI_EXIT_USAGE)
+	AssertCliCommand(t, []string{"compare", "-a", filepath.Join(dir, "none.rle"), "-b", filepath.Join(dir, "blinker.rle")}, CLI_EXIT_ERROR)
+}
+
+// exp is the match as a string or empty if the patterns should be different
+func AssertCompare(t *testing.T, id string, a, b []int64, period int, exp string) {
+	m := ComparePatterns(a, b, period, NewConwayRule())
+	if m == nil {
+		if exp != "" {
+			t.Errorf("%s: patterns should match '%s'", id, exp)
+		}
+		return
+	}
+	if m.String() != exp {
+		t.Errorf("%s: match '%s' expected '%s'", id, m.String(), exp)
+	}
+	if !apgEqual(m.Apply(a), b) && m.Phase == 0 {
+		t.Errorf("%s: applying the match does not give B", id)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type ConvertOptions struct {
+	Format    PatternFormat // The format to write
+	Normalise bool          // Move the cells so the min x and y are 0
+	Canonical bool          // Rotate, flip and move to the canonical form as the GUI save does. See CanonicalCoords. Implies Normalise
+	Name      string        // If not empty replaces the name from the source file
+	Owner     string        // If not empty replaces the owner from the source file
+	Comment   string        // If not empty replaces all of the comments from the source file
+}
+
+// Apply the options to a loaded pattern.
+func (opts *ConvertOptions) Apply(rle *RLE) {
+	coords := rle.coords
+	if opts.Canonical {
+		coords, _ = CanonicalCoords(coords)
+	} else if opts.Normalise {
+		coords, _, _ = POCNormaliseCoords(coords)
+	}
+	rle.SetCoords(coords)
+	if opts.Name != "" {
+		rle.name = opts.Name
+	}
+	if opts.Owner != "" {
+		rle.owner = opts.Owner
+	}
+	if opts.Comment != "" {
+		rle.comment = opts.Comment
+		rle.comments = []string{opts.Comment}
+	}
+	if rle.name == "" {
+		base := filepath.Base(rle.fileName)
+		rle.name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+}
+
+// Load a pattern in any format and save it in the format given in the options.
+func ConvertPattern(inFile, outFile string, opts *ConvertOptions) error {
+	rle, err := LoadPattern(inFile)
+	if err != nil {
+		return err
+	}
+	opts.Apply(rle)
+	return rle.SaveAs(outFile, opts.Format)
+}
+
+// Convert every pattern file in the inDir tree and write it to the same relative path under outDir with the extension of the format.
+// Directories starting with '.' or '_' are skipped as in the file browser. outDir is skipped if it is inside inDir.
+// Files that fail, or that would write the same output file as another input (a.cells and a.lif), are reported and skipped.
+// report is called for each file. Returns the number of files converted and the number that failed.
+func ConvertDir(inDir, outDir string, opts *ConvertOptions, report func(inFile, outFile string, err error)) (int, int, error) {
+	inFiles := make([]string, 0)
+	outFiles := make(map[string]string)
+	outCount := make(map[string]int)
+	err := filepath.WalkDir(inDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != inDir && (strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(d.Name(), "_") || sameFile(p, outDir)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !IsPatternFile(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(inDir, p)
+		if err != nil {
+			return err
+		}
+		outFile := filepath.Join(outDir, strings.TrimSuffix(rel, filepath.Ext(rel))+opts.Format.Ext())
+		inFiles = append(inFiles, p)
+		outFiles[p] = outFile
+		outCount[outFile]++
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return 0, 0, err
+	}
+	ok, failed := 0, 0
+	for _, inFile := range inFiles {
+		outFile := outFiles[inFile]
+		if outCount[outFile] > 1 {
+			err = fmt.Errorf("output file '%s' would be written by more than one input file. '%s' is not converted", outFile, inFile)
+		} else if sameFile(inFile, outFile) {
+			err = fmt.Errorf("output file '%s' would overwrite the input file", outFile)
+		} else if err = os.MkdirAll(filepath.Dir(outFile), 0755); err == nil {
+			err = ConvertPattern(inFile, outFile, opts)
+		}
+		if err != nil {
+			failed++
+		} else {
+			ok++
+		}
+		report(inFile, outFile, err)
+	}
+	return ok, failed, nil
+}
+
+func sameFile(a, b string) bool {
+	aa, errA := filepath.Abs(a)
+	bb, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && aa == bb
+}
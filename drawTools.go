@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+type LifeTool int
+
+// The tools used by the primary mouse button in the Life view.
+const (
+	TOOL_SELECT LifeTool = iota // Click toggles a cell. Drag selects cells
+	TOOL_PENCIL                 // Click or drag adds cells
+	TOOL_ERASER                 // Click or drag removes cells
+	TOOL_LINE                   // Drag adds a straight line of cells
+	TOOL_RECT                   // Drag adds the outline of a rectangle
+	TOOL_FILL                   // Drag adds a filled rectangle
+	TOOL_RANDOM                 // Drag adds random cells in a rectangle. See TOOL_DENSITIES
+	TOOL_COUNT
+)
+
+var (
+	toolNames      = []string{"Select", "Pencil", "Eraser", "Line", "Rectangle", "Filled rectangle", "Random fill"}
+	TOOL_DENSITIES = []string{"10%", "25%", "37%", "50%", "75%"} // Choices for random fill. 37% is about the density of a random soup
+)
+
+func (t LifeTool) String() string {
+	if t < 0 || t >= TOOL_COUNT {
+		return fmt.Sprintf("tool(%d)", int(t))
+	}
+	return toolNames[t]
+}
+
+// The names of the tools in order. Used for the tool selector.
+func LifeToolNames() []string {
+	return toolNames
+}
+
+// Parse a name as returned by String(). Case is ignored
+func ParseLifeTool(name string) (LifeTool, error) {
+	for i, n := range toolNames {
+		if strings.EqualFold(n, strings.TrimSpace(name)) {
+			return LifeTool(i), nil
+		}
+	}
+	return TOOL_SELECT, fmt.Errorf("unknown tool '%s'. Use one of %s", name, strings.Join(toolNames, ","))
+}
+
+// True if the tool changes cells as the mouse is dragged. Other tools change cells when the mouse is released.
+func (t LifeTool) Paints() bool {
+	return t == TOOL_PENCIL || t == TOOL_ERASER
+}
+
+/*
+The cells for a shape tool dragged from x1,y1 to x2,y2. density (0 to 1) and rnd are only used by TOOL_RANDOM.
+Pencil and eraser return the line between the points. Select returns no cells.
+*/
+func (t LifeTool) Cells(x1, y1, x2, y2 int64, density float64, rnd *rand.Rand) []int64 {
+	switch t {
+	case TOOL_PENCIL, TOOL_ERASER, TOOL_LINE:
+		return LineCells(x1, y1, x2, y2)
+	case TOOL_RECT:
+		return RectCells(x1, y1, x2, y2, false)
+	case TOOL_FILL:
+		return RectCells(x1, y1, x2, y2, true)
+	case TOOL_RANDOM:
+		return RandomCells(x1, y1, x2, y2, density, rnd)
+	}
+	return make([]int64, 0)
+}
+
+/*
+The cells shown while a shape tool is dragged. Rectangles (filled, random or not) show only the outline
+so the time taken while dragging does not depend on the area. The cells are added when the mouse is released.
+*/
+func (t LifeTool) PreviewCells(x1, y1, x2, y2 int64) []int64 {
+	switch t {
+	case TOOL_RECT, TOOL_FILL, TOOL_RANDOM:
+		return RectCells(x1, y1, x2, y2, false)
+	}
+	return t.Cells(x1, y1, x2, y2, 0, nil)
+}
+
+// The cells on a straight line from x1,y1 to x2,y2 including both ends (Bresenham's algorithm)
+func LineCells(x1, y1, x2, y2 int64) []int64 {
+	dx := absInt64(x2 - x1)
+	dy := -absInt64(y2 - y1)
+	sx, sy := int64(1), int64(1)
+	if x2 < x1 {
+		sx = -1
+	}
+	if y2 < y1 {
+		sy = -1
+	}
+	resp := make([]int64, 0, 2*(maxInt64(dx, -dy)+1))
+	e := dx + dy
+	for {
+		resp = append(resp, x1, y1)
+		if x1 == x2 && y1 == y2 {
+			return resp
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e = e + dy
+			x1 = x1 + sx
+		}
+		if e2 <= dx {
+			e = e + dx
+			y1 = y1 + sy
+		}
+	}
+}
+
+// The cells of a rectangle with corners x1,y1 and x2,y2 (in any order). Either the outline or all of the cells
+func RectCells(x1, y1, x2, y2 int64, filled bool) []int64 {
+	x1, x2 = minInt64(x1, x2), maxInt64(x1, x2)
+	y1, y2 = minInt64(y1, y2), maxInt64(y1, y2)
+	resp := make([]int64, 0)
+	for y := y1; y <= y2; y++ {
+		if filled || y == y1 || y == y2 {
+			for x := x1; x <= x2; x++ {
+				resp = append(resp, x, y)
+			}
+		} else {
+			// Only the ends of the rows between the top and bottom
+			resp = append(resp, x1, y)
+			if x2 != x1 {
+				resp = append(resp, x2, y)
+			}
+		}
+	}
+	return resp
+}
+
+// Each cell of the rectangle with corners x1,y1 and x2,y2 is added with a probability of density (0 to 1)
+func RandomCells(x1, y1, x2, y2 int64, density float64, rnd *rand.Rand) []int64 {
+	x1, x2 = minInt64(x1, x2), maxInt64(x1, x2)
+	y1, y2 = minInt64(y1, y2), maxInt64(y1, y2)
+	resp := make([]int64, 0)
+	for y := y1; y <= y2; y++ {
+		for x := x1; x <= x2; x++ {
+			if rnd.Float64() < density {
+				resp = append(resp, x, y)
+			}
+		}
+	}
+	return resp
+}
+
+// Parse one of TOOL_DENSITIES to a number from 0 to 1
+func ParseToolDensity(s string) (float64, error) {
+	var pc float64
+	if _, err := fmt.Sscanf(strings.TrimSpace(s), "%f%%", &pc); err != nil || pc < 0 || pc > 100 {
+		return 0, fmt.Errorf("density '%s' must be a percentage from 0%% to 100%%", s)
+	}
+	return pc / 100, nil
+}
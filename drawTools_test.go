@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestDrawToolCells(t *testing.T) {
+	AssertToolCells(t, TOOL_LINE, 0, 0, 0, 0, "0,0")
+	AssertToolCells(t, TOOL_LINE, 0, 0, 3, 0, "0,0 1,0 2,0 3,0")
+	AssertToolCells(t, TOOL_LINE, 2, 2, 0, 0, "2,2 1,1 0,0")
+	AssertToolCells(t, TOOL_PENCIL, 0, 0, 4, 2, "0,0 1,1 2,1 3,2 4,2")
+	AssertToolCells(t, TOOL_LINE, 0, 0, -1, 3, "0,0 0,1 -1,2 -1,3")
+	AssertToolCells(t, TOOL_RECT, 2, 2, 0, 0, "0,0 1,0 2,0 0,1 2,1 0,2 1,2 2,2")
+	AssertToolCells(t, TOOL_FILL, 0, 0, 1, 1, "0,0 1,0 0,1 1,1")
+	AssertToolCells(t, TOOL_SELECT, 0, 0, 1, 1, "")
+	AssertToolCells(t, TOOL_RECT, 0, 0, 0, 2, "0,0 0,1 0,2")
+
+	// Only the outline is shown while dragging filled and random rectangles
+	for _, tool := range []LifeTool{TOOL_RECT, TOOL_FILL, TOOL_RANDOM} {
+		if got := fmt.Sprint(tool.PreviewCells(2, 2, 0, 0)); got != "[0 0 1 0 2 0 0 1 2 1 0 2 1 2 2 2]" {
+			t.Errorf("%s preview: Expected the outline. Actual %s", tool, got)
+		}
+	}
+	if got := fmt.Sprint(TOOL_LINE.PreviewCells(0, 0, 2, 0)); got != "[0 0 1 0 2 0]" {
+		t.Errorf("Line preview: Expected the line. Actual %s", got)
+	}
+	// A large outline does not visit the inside
+	if n := len(TOOL_FILL.PreviewCells(0, 0, 99999, 99999)) / 2; n != 4*99999 {
+		t.Errorf("Large preview: Expected %d cells. Actual %d", 4*99999, n)
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	if n := len(RandomCells(0, 0, 99, 99, 0.37, rnd)) / 2; n < 3400 || n > 4000 {
+		t.Errorf("Random: Expected about 3700 cells. Actual %d", n)
+	}
+	if n := len(TOOL_RANDOM.Cells(0, 0, 9, 9, 1, rnd)); n != 200 {
+		t.Errorf("Random: Expected all 100 cells for density 1. Actual %d", n/2)
+	}
+}
+
+func TestDrawToolParse(t *testing.T) {
+	for i, name := range LifeToolNames() {
+		tool, err := ParseLifeTool(name)
+		if err != nil || tool != LifeTool(i) {
+			t.Errorf("Tool '%s' parsed to %s expected %s", name, tool, LifeTool(i))
+		}
+	}
+	if tool, _ := ParseLifeTool(" random FILL "); tool != TOOL_RANDOM {
+		t.Errorf("Tool 'random FILL' parsed to %s", tool)
+	}
+	if _, err := ParseLifeTool("brush"); err == nil {
+		t.Errorf("Tool 'brush' did not return an error")
+	}
+	for _, d := range TOOL_DENSITIES {
+		if _, err := ParseToolDensity(d); err != nil {
+			t.Errorf("Density %s returned %s", d, err.Error())
+		}
+	}
+	if d, _ := ParseToolDensity("37%"); d != 0.37 {
+		t.Errorf("Density 37%% returned %f", d)
+	}
+	if _, err := ParseToolDensity("120%"); err == nil || err.Error() != "density '120%' must be a percentage from 0% to 100%" {
+		t.Errorf("Density 120%% returned %v", err)
+	}
+}
+
+func AssertToolCells(t *testing.T, tool LifeTool, x1, y1, x2, y2 int64, exp string) {
+	got := ""
+	cells := tool.Cells(x1, y1, x2, y2, 0, nil)
+	for i := 0; i < len(cells); i = i + 2 {
+		if i > 0 {
+			got = got + " "
+		}
+		got = got + fmt.Sprintf("%d,%d", cells[i], cells[i+1])
+	}
+	if got != exp {
+		t.Errorf("%s %d,%d to %d,%d: Expected '%s' actual '%s'", tool, x1, y1, x2, y2, exp, got)
+	}
+}
@@ -166,6 +166,13 @@ func (w *FileBrowserWidget) InputSaveForm(prompt string) *fyne.Container {
 	return w.saveForm
 }
 
+// Change the prompt on the save form. Allows the same form to be used for different file types
+func (w *FileBrowserWidget) SetSavePrompt(prompt string) {
+	if w.saveLabel != nil {
+		w.saveLabel.SetText(prompt)
+	}
+}
+
 func (w *FileBrowserWidget) GetSelected() (string, FileBrowserLineType) {
 	for _, o := range w.objects {
 		ow, ok := o.(*FileBrowserWidgetLine)
@@ -237,6 +244,12 @@ func (mc *FileBrowserWidget) SetPath(newPath string) {
 	mc.objects = append(coDir, coFile...)
 }
 
+// Read the current directory again. Used when the text for the files may have changed
+func (mc *FileBrowserWidget) Reload() {
+	mc.SetPath(mc.currentPath)
+	mc.Refresh()
+}
+
 func (mc *FileBrowserWidget) selectByMouse(x, y float32) *FileBrowserWidgetLine {
 	var fbwlSel *FileBrowserWidgetLine
 	for _, o := range mc.objects {
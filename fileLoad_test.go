@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path"
 	"strings"
 	"testing"
 )
@@ -30,8 +32,9 @@ func TestFileEncode(t *testing.T) {
 		t.Errorf("RLE File load failed. %e", err)
 	}
 	enc, w, h := rle.Encode()
-	if rle.encoded != enc {
-		t.Errorf("RLE File Encode failed. \n%s\n%s", rle.encoded, enc)
+	exp := "5b2o$6bo$4bo$2obob4o$2obo5bobo$3bo2b3ob2o$3bo4bo$4b3obo$7bo$6bo$6b2o!"
+	if exp != enc {
+		t.Errorf("RLE File Encode failed. \n%s\n%s", exp, enc)
 	}
 	if w != 12 {
 		t.Errorf("RLE File Encode failed. Expected width %d Actual Width %d", 12, w)
@@ -41,11 +44,143 @@ func TestFileEncode(t *testing.T) {
 	}
 
 	saveRle := NewRLESave("RLESave", rle.coords, "owner", "desc")
-	if rle.decoded != saveRle.decoded {
-		t.Errorf("RLE File Encode failed. Decoded expected \n%s Actual \n%s", rle.decoded, saveRle.decoded)
+	if rle.Decoded() != saveRle.Decoded() {
+		t.Errorf("RLE File Encode failed. Decoded expected \n%s Actual \n%s", rle.Decoded(), saveRle.Decoded())
 	}
-	if rle.encoded != saveRle.encoded {
-		t.Errorf("RLE File Encode failed. Encoded expected \n%s Actual \n%s", rle.encoded, saveRle.encoded)
+	if exp != saveRle.encoded {
+		t.Errorf("RLE File Encode failed. Encoded expected \n%s Actual \n%s", exp, saveRle.encoded)
+	}
+	AssertEncode(t, []int64{}, "!")
+	AssertEncode(t, []int64{5, 5}, "o!")
+	AssertEncode(t, []int64{5, 5, 5, 5, 6, 5}, "2o!")
+	AssertEncode(t, []int64{0, 0, 0, 4, 2, 4}, "o4$obo!")
+	AssertEncode(t, []int64{2, 2, 0, 0, 1, 0}, "2o2$2bo!")
+}
+
+func TestFileEncodeWrap(t *testing.T) {
+	assertStr(t, "12b\n3o$\n!", RLEWrapLines("12b3o$!", 3))
+	assertStr(t, "12b3o\n$!", RLEWrapLines("12b3o$!", 5))
+	assertStr(t, "12b3o$!", RLEWrapLines("12b3o$!", 70))
+	assertStr(t, "123456b\n!", RLEWrapLines("123456b!", 3))
+
+	coords := make([]int64, 0)
+	for i := int64(0); i < 5000; i++ {
+		coords = append(coords, (i*7919)%997, (i*104729)%991)
+	}
+	rle := NewRLESave("Wrap", coords, "owner", "desc")
+	content := rle.SaveFileContent()
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if len(line) > RLE_LINE_LENGTH {
+			t.Errorf("RLE File Encode failed. Line %d is %d long", i+1, len(line))
+		}
+	}
+	if !strings.Contains(content, "\nx = 997, y = 991, rule = B3/S23\n") {
+		t.Errorf("RLE File Encode failed. Header is incorrect\n%s", strings.Join(lines[:5], "\n"))
+	}
+	loaded, err := NewRleReader(strings.NewReader(content), "Wrap")
+	if err != nil {
+		t.Errorf("RLE File Encode failed. Reload error %s", err.Error())
+		return
+	}
+	if !apgEqual(coords, loaded.coords) {
+		t.Errorf("RLE File Encode failed. Reloaded cells do not match")
+	}
+}
+
+func AssertEncode(t *testing.T, coords []int64, exp string) {
+	enc, _, _ := RLEEncodeCoords(coords)
+	if enc != exp {
+		t.Errorf("RLE Encode failed. Expected '%s' actual '%s'", exp, enc)
+	}
+}
+
+func TestFileDecodeErrors(t *testing.T) {
+	AssertDecodeError(t, "x = 3, y = 1\n3o!", "")
+	AssertDecodeError(t, "#N Name\n#C Comment\nx = 3, y = 1, rule = B3/S23\n3o!", "")
+	AssertDecodeError(t, "3o$\n2b2o\n!", "")
+	AssertDecodeError(t, "x = 3, y = 1\n3o$2", "test:2:4: run count 2 is not followed by b, o, $ or !")
+	AssertDecodeError(t, "x = 3, y = 1\n3o$\n b3q!", "test:3:4: unexpected character 'q'. Expected a number, b, o, $ or !")
+	AssertDecodeError(t, "x = 3, y = z1\n3o!", "test:1:12: header value for y 'z1' is not a positive number")
+	AssertDecodeError(t, "x = 3, y\n3o!", "test:1:7: header item 'y' is not in the form name = value")
+	AssertDecodeError(t, "x = 3, y = 1\n99999999o!", "test:2:1: run count is greater than 1000000")
+	AssertDecodeError(t, "x = 3, y = 1\n3o!\nAnything after the end is ignored", "")
+	AssertDecodeError(t, "  #N Name\n\t#C Comment\n x = 3, y = 1\n3o!", "")
+	AssertDecodeError(t, " #C Comment\nx = 3, y = 1\n 3q!", "test:3:3: unexpected character 'q'. Expected a number, b, o, $ or !")
+}
+
+func TestFileDecode(t *testing.T) {
+	rle, err := NewRleReader(strings.NewReader("#N Glider\n#O Richard K. Guy\n#C First\n#C Second\nx = 3, y = 3, rule = B36/S23\nbo$2bo$3o!"), "test")
+	if err != nil {
+		t.Errorf("RLE decode failed. %s", err.Error())
+		return
+	}
+	assertStr(t, "Glider", rle.name)
+	assertStr(t, "Richard K. Guy", rle.owner)
+	assertStr(t, "First", rle.comment)
+	assertStr(t, "B36/S23", rle.rule)
+	assertStr(t, "bo$2bo$3o!", rle.encoded)
+	assertStr(t, "| |O| |\n| | |O|\n|O|O|O|\n", rle.Decoded())
+	if rle.width != 3 || rle.height != 3 {
+		t.Errorf("RLE decode failed. Expected width 3 height 3 actual %d %d", rle.width, rle.height)
+	}
+	if fmt.Sprint(rle.coords) != "[1 0 2 1 0 2 1 2 2 2]" {
+		t.Errorf("RLE decode failed. Expected coords [1 0 2 1 0 2 1 2 2 2] actual %v", rle.coords)
+	}
+	// Leading blanks before comments are skipped
+	rle, err = NewRleReader(strings.NewReader("  #N Blinker\n\t#C Indented\nx = 3, y = 1\n3o!"), "test")
+	if err != nil {
+		t.Fatalf("RLE decode with indented comments failed. %s", err.Error())
+	}
+	assertStr(t, "Blinker", rle.name)
+	assertStr(t, "Indented", rle.comment)
+}
+
+func FuzzFileDecode(f *testing.F) {
+	files, err := os.ReadDir("testdata")
+	if err != nil {
+		f.Fatalf("Unable to read testdata. %s", err.Error())
+	}
+	for _, fil := range files {
+		if strings.HasSuffix(fil.Name(), ".rle") {
+			b, err := os.ReadFile(path.Join("testdata", fil.Name()))
+			if err != nil {
+				f.Fatalf("Unable to read %s. %s", fil.Name(), err.Error())
+			}
+			f.Add(string(b))
+		}
+	}
+	f.Add(" #N Name\n\t#C Comment\n x = 3, y = 1\n3o!")
+	f.Add("#C Comment\r\n  \r\n#C More\r\nx = 1, y = 1\r\no!")
+	f.Fuzz(func(t *testing.T, s string) {
+		rle, err := NewRleReader(strings.NewReader(s), "fuzz")
+		if err != nil {
+			if _, ok := err.(*RLEError); !ok {
+				t.Errorf("Expected an RLEError. Actual %T %s", err, err.Error())
+			}
+			return
+		}
+		if len(rle.coords)%2 != 0 {
+			t.Errorf("Odd number of coords %d", len(rle.coords))
+		}
+		for i := 0; i < len(rle.coords); i = i + 2 {
+			if rle.coords[i] < rle.minX || rle.coords[i] > rle.maxX || rle.coords[i+1] < rle.minY || rle.coords[i+1] > rle.maxY {
+				t.Errorf("Cell %d,%d is outside the bounds %d,%d %d,%d", rle.coords[i], rle.coords[i+1], rle.minX, rle.minY, rle.maxX, rle.maxY)
+			}
+		}
+	})
+}
+
+func AssertDecodeError(t *testing.T, content, exp string) {
+	_, err := NewRleReader(strings.NewReader(content), "test")
+	if err == nil {
+		if exp != "" {
+			t.Errorf("RLE decode did not fail. Expected '%s'", exp)
+		}
+		return
+	}
+	if err.Error() != exp {
+		t.Errorf("RLE decode failed. Expected '%s' actual '%s'", exp, err.Error())
 	}
 }
 
@@ -3,32 +3,48 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
-	"unicode"
+)
+
+const (
+	RLE_DEFAULT_RULE = "B3/S23"
+	RLE_MAX_RUN      = 1000000 // Largest run count accepted when decoding
+	RLE_LINE_LENGTH  = 70      // Maximum length of a line of encoded cells when saving
 )
 
 type RLE struct {
 	fileName string
-	decoded  string
+	decoded  string // Built on demand by Decoded()
 	coords   []int64
 	encoded  string
 	name     string
 	owner    string
-	comment  string
+	comment  string   // The first #C comment
+	comments []string // All of the #C comments. Written in order when saving
+	rule     string
+	width    int64 // From the header line
+	height   int64 // From the header line
 	minX     int64
 	minY     int64
 	maxX     int64
 	maxY     int64
 }
 
+// A new pattern to save. The comments are the created date and the comment (if not empty)
 func NewRLESave(fn string, coords []int64, owner, comment string) *RLE {
-	rle := &RLE{fileName: fn, coords: coords, owner: owner, comment: comment}
+	rle := &RLE{fileName: fn, coords: coords, owner: owner, comment: comment, rule: RLE_DEFAULT_RULE}
+	rle.comments = []string{fmt.Sprintf("Created: %s", time.Now().Format("Monday January 2 2006"))}
+	if comment != "" {
+		rle.comments = append(rle.comments, comment)
+	}
 	fnlc := strings.ToLower(fn)
 	ext := path.Ext(fnlc)
 	if ext != ".rle" {
@@ -37,9 +53,10 @@ func NewRLESave(fn string, coords []int64, owner, comment string) *RLE {
 	}
 	_, rle.name = path.Split(fn)
 	rle.fileName = fn
-	enc, _, _ := rle.Encode()
+	enc, w, h := rle.Encode()
 	rle.encoded = enc
-	rle.decoded, _ = rle.rleDecodeString(rle.encoded)
+	rle.width = w
+	rle.height = h
 	return rle
 }
 
@@ -53,80 +70,263 @@ func (rle *RLE) Save() error {
 	return err
 }
 
+// All of the comments. If there are none the comment is used
+func (rle *RLE) Comments() []string {
+	if len(rle.comments) == 0 && rle.comment != "" {
+		return []string{rle.comment}
+	}
+	return rle.comments
+}
+
 func (rle *RLE) SaveFileContent() string {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("#N %s\n", rle.name))
-	sb.WriteString(fmt.Sprintf("#O %s\n", rle.owner))
-	sb.WriteString(fmt.Sprintf("#C Created: %s\n", time.Now().Format("Monday January 2 2006")))
-	sb.WriteString(fmt.Sprintf("#C %s\n", rle.comment))
-	sb.WriteString("x = 0, y = 0, rule = B3/S23\n")
-	sb.WriteString(rle.encoded)
+	if rle.owner != "" {
+		sb.WriteString(fmt.Sprintf("#O %s\n", rle.owner))
+	}
+	for _, c := range rle.Comments() {
+		sb.WriteString(fmt.Sprintf("#C %s\n", c))
+	}
+	sb.WriteString(fmt.Sprintf("x = %d, y = %d, rule = %s\n", rle.width, rle.height, rle.rule))
+	sb.WriteString(RLEWrapLines(rle.encoded, RLE_LINE_LENGTH))
+	sb.WriteString("\n")
 	return sb.String()
 }
 
+// An error in the content of an RLE file.
+// Line and Col are 1 based and point to the character that caused the error.
+type RLEError struct {
+	FileName string
+	Line     int
+	Col      int
+	Msg      string
+}
+
+func (e *RLEError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.FileName, e.Line, e.Col, e.Msg)
+}
+
+// Streaming RLE decoder. Reads one byte at a time and keeps track of the line and column
+type rleDecoder struct {
+	in       *bufio.Reader
+	rle      *RLE
+	encoded  strings.Builder
+	line     int
+	col      int
+	x        int64
+	y        int64
+	header   bool // The 'x = ...' header line has been read
+	body     bool // The first character of the encoded cells has been read
+	finished bool // The '!' has been read
+}
+
 func NewRleFile(fileName string) (*RLE, error) {
-	rle := &RLE{fileName: fileName}
-	file, err := os.Open(rle.fileName)
+	file, err := os.Open(fileName)
 	if err != nil {
 		return nil, err
 	}
-	scanner := bufio.NewScanner(file)
-	var sb strings.Builder
-	ln := 0
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "#N") {
-			rle.name = strings.TrimSpace(line[2:])
-		} else {
-			if strings.HasPrefix(line, "#C") {
-				if rle.comment == "" {
-					rle.comment = strings.TrimSpace(line[2:])
-				}
-			} else {
-				if strings.HasPrefix(line, "#O") {
-					rle.owner = strings.TrimSpace(line[2:])
-				} else {
-					if !strings.HasPrefix(line, "#") {
-						if ln > 0 {
-							sb.WriteString(line)
-						}
-						ln++
-					}
-				}
-			}
-		}
+	defer file.Close()
+	return NewRleReader(file, fileName)
+}
+
+// Read an RLE from any reader. fileName is used to identify the source in errors.
+func NewRleReader(r io.Reader, fileName string) (*RLE, error) {
+	dec := &rleDecoder{in: bufio.NewReader(r), rle: &RLE{fileName: fileName, rule: RLE_DEFAULT_RULE, coords: make([]int64, 0)}, line: 1, col: 0}
+	err := dec.decode()
+	if err != nil {
+		return nil, err
 	}
-	rle.encoded = sb.String()
-	rle.decoded, rle.coords = rle.rleDecodeString(sb.String())
+	rle := dec.rle
+	rle.encoded = dec.encoded.String()
 	if len(rle.coords) == 0 {
 		rle.minX = 0
 		rle.minY = 0
 		rle.maxX = 0
 		rle.maxY = 0
-	} else {
-		rle.minX = math.MaxInt64
-		rle.minY = math.MaxInt64
-		rle.maxX = math.MinInt64
-		rle.maxY = math.MinInt64
 	}
-	for i := 0; i < len(rle.coords); i = i + 2 {
-		if rle.coords[i] < rle.minX {
-			rle.minX = rle.coords[i]
+	return rle, nil
+}
+
+func (dec *rleDecoder) errorAt(line, col int, format string, args ...interface{}) *RLEError {
+	return &RLEError{FileName: dec.rle.fileName, Line: line, Col: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Read the rest of the current line (the new line is not returned).
+func (dec *rleDecoder) restOfLine() (string, error) {
+	s, err := dec.in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if strings.HasSuffix(s, "\n") {
+		dec.line++
+		dec.col = 0
+		s = s[:len(s)-1]
+	}
+	return strings.TrimSuffix(s, "\r"), nil
+}
+
+func (dec *rleDecoder) decode() error {
+	count := 0
+	countLine := 0
+	countCol := 0
+	lineStart := true
+	for !dec.finished {
+		b, err := dec.in.ReadByte()
+		if err == io.EOF {
+			break
 		}
-		if rle.coords[i] > rle.maxX {
-			rle.maxX = rle.coords[i]
+		if err != nil {
+			return err
 		}
-		if rle.coords[i+1] < rle.minY {
-			rle.minY = rle.coords[i+1]
+		dec.col++
+		if lineStart && !dec.body {
+			if b == ' ' || b == '\t' {
+				// Leading blanks before a comment or the header are skipped
+				continue
+			}
+			if b == '#' {
+				line, err := dec.restOfLine()
+				if err != nil {
+					return err
+				}
+				dec.comment(line)
+				continue
+			}
+			if b == 'x' && !dec.header {
+				line, col := dec.line, dec.col
+				rest, err := dec.restOfLine()
+				if err != nil {
+					return err
+				}
+				err = dec.parseHeader("x"+rest, line, col)
+				if err != nil {
+					return err
+				}
+				continue
+			}
 		}
-		if rle.coords[i+1] > rle.maxY {
-			rle.maxY = rle.coords[i+1]
+		lineStart = false
+		switch {
+		case b == '\n':
+			dec.line++
+			dec.col = 0
+			lineStart = true
+		case b == ' ' || b == '\t' || b == '\r':
+		case b >= '0' && b <= '9':
+			if count == 0 {
+				countLine, countCol = dec.line, dec.col
+			}
+			count = count*10 + int(b-'0')
+			if count > RLE_MAX_RUN {
+				return dec.errorAt(countLine, countCol, "run count is greater than %d", RLE_MAX_RUN)
+			}
+			dec.body = true
+			dec.encoded.WriteByte(b)
+		case b == 'b' || b == '.' || b == 'o' || b == '$' || b == '!':
+			n := count
+			if n == 0 {
+				n = 1
+			}
+			count = 0
+			dec.body = true
+			dec.encoded.WriteByte(b)
+			dec.cells(b, int64(n))
+		default:
+			return dec.errorAt(dec.line, dec.col, "unexpected character '%c'. Expected a number, b, o, $ or !", b)
 		}
 	}
-	if scanner.Err() != nil {
-		return nil, scanner.Err()
+	if count > 0 {
+		return dec.errorAt(countLine, countCol, "run count %d is not followed by b, o, $ or !", count)
 	}
-	return rle, nil
+	return nil
+}
+
+// Add n cells (or dead cells or lines) at the current position
+func (dec *rleDecoder) cells(tag byte, n int64) {
+	rle := dec.rle
+	switch tag {
+	case 'b', '.':
+		dec.x = dec.x + n
+	case 'o':
+		if len(rle.coords) == 0 {
+			rle.minX, rle.minY, rle.maxX, rle.maxY = dec.x, dec.y, dec.x, dec.y
+		}
+		if dec.x < rle.minX {
+			rle.minX = dec.x
+		}
+		if dec.x+n-1 > rle.maxX {
+			rle.maxX = dec.x + n - 1
+		}
+		if dec.y < rle.minY {
+			rle.minY = dec.y
+		}
+		if dec.y > rle.maxY {
+			rle.maxY = dec.y
+		}
+		for i := int64(0); i < n; i++ {
+			rle.coords = append(rle.coords, dec.x, dec.y)
+			dec.x++
+		}
+	case '$':
+		dec.y = dec.y + n
+		dec.x = 0
+	case '!':
+		dec.finished = true
+	}
+}
+
+// Comment lines. The first #C comment is the comment. All are kept in comments
+func (dec *rleDecoder) comment(line string) {
+	if len(line) == 0 {
+		return
+	}
+	text := strings.TrimSpace(line[1:])
+	switch line[0] {
+	case 'N':
+		dec.rle.name = text
+	case 'O':
+		dec.rle.owner = text
+	case 'C', 'c':
+		if dec.rle.comment == "" {
+			dec.rle.comment = text
+		}
+		dec.rle.comments = append(dec.rle.comments, text)
+	}
+}
+
+// Parse the header line 'x = 12, y = 11, rule = B3/S23'.
+// col is the column of the 'x' so errors can point at the value in error.
+func (dec *rleDecoder) parseHeader(line string, lineNo, col int) error {
+	dec.header = true
+	pos := 0
+	for _, part := range strings.Split(line, ",") {
+		eq := strings.Index(part, "=")
+		if eq < 0 {
+			return dec.errorAt(lineNo, col+pos, "header item '%s' is not in the form name = value", strings.TrimSpace(part))
+		}
+		name := strings.TrimSpace(part[:eq])
+		value := strings.TrimSpace(part[eq+1:])
+		valueCol := col + pos + eq + 1 + strings.Index(part[eq+1:], value)
+		switch name {
+		case "x", "y":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < 0 {
+				return dec.errorAt(lineNo, valueCol, "header value for %s '%s' is not a positive number", name, value)
+			}
+			if name == "x" {
+				dec.rle.width = n
+			} else {
+				dec.rle.height = n
+			}
+		case "rule":
+			if value == "" {
+				return dec.errorAt(lineNo, valueCol, "header rule is empty")
+			}
+			dec.rle.rule = value
+		}
+		pos = pos + len(part) + 1
+	}
+	return nil
 }
 
 func (rle *RLE) Center() (int64, int64) {
@@ -136,113 +336,121 @@ func (rle *RLE) Center() (int64, int64) {
 	return (rle.maxX - rle.minX) / 2, (rle.maxY - rle.minY) / 2
 }
 
-func (rle *RLE) rleDecodeString(rleStr string) (string, []int64) {
-	var result strings.Builder
-	coords := make([]int64, 0)
-	for len(rleStr) > 0 {
-		letterIndex := strings.IndexFunc(rleStr, func(r rune) bool { return !unicode.IsDigit(r) })
-		multiply := 1
-		if letterIndex != 0 {
-			multiply, _ = strconv.Atoi(rleStr[:letterIndex])
+// The cells as a grid of '|O' and '| ' for debugging.
+// It is only built when requested as it can be very large.
+func (rle *RLE) Decoded() string {
+	if rle.decoded != "" || len(rle.coords) == 0 {
+		return rle.decoded
+	}
+	var maxX, maxY int64 = 0, 0
+	for i := 0; i < len(rle.coords); i = i + 2 {
+		if rle.coords[i] > maxX {
+			maxX = rle.coords[i]
+		}
+		if rle.coords[i+1] > maxY {
+			maxY = rle.coords[i+1]
+		}
+	}
+	w := maxX + 1
+	grid := make([]bool, w*(maxY+1))
+	for i := 0; i < len(rle.coords); i = i + 2 {
+		if rle.coords[i] >= 0 && rle.coords[i+1] >= 0 {
+			grid[rle.coords[i+1]*w+rle.coords[i]] = true
 		}
-		result.WriteString(strings.Repeat(string(rleStr[letterIndex]), multiply))
-		rleStr = rleStr[letterIndex+1:]
 	}
-	out := result.String()
-
 	var sb strings.Builder
-	count := 0
-	width := 0
-	var y int64 = 0
-	var x int64 = 0
-	for _, c := range out {
-		switch c {
-		case '$':
-			y++
-			x = 0
-			if count == 0 {
-				for i := 0; i <= width; i++ {
-					sb.WriteString("| ")
-				}
-				sb.WriteString("\n")
+	for y := int64(0); y <= maxY; y++ {
+		for x := int64(0); x < w; x++ {
+			if grid[y*w+x] {
+				sb.WriteString("|O")
 			} else {
-				sb.WriteString("|\n")
-				width = count
-			}
-			count = 0
-		case 'b':
-			sb.WriteString("| ")
-			count++
-			x++
-		case 'o':
-			coords = append(coords, x)
-			coords = append(coords, y)
-			sb.WriteString("|O")
-			count++
-			x++
-		case '!':
-			for i := 0; i <= (width - count); i++ {
 				sb.WriteString("| ")
 			}
 		}
+		sb.WriteString("|\n")
 	}
-	rle.maxX = x
-	rle.minY = y
-	return sb.String(), coords
+	rle.decoded = sb.String()
+	return rle.decoded
 }
 
 func (rle *RLE) Encode() (string, int64, int64) {
 	return RLEEncodeCoords(rle.coords)
 }
 
+// Encode a list of x,y coords as RLE. The coords are normalised first.
+// The cells are sorted by row then column so each cell is visited once.
+// Trailing dead cells are not written and runs of empty lines are merged (for example 3$).
+// Returns the encoded cells (on a single line) the width and the height.
 func RLEEncodeCoords(coords []int64) (string, int64, int64) {
+	if len(coords) == 0 {
+		return "!", 0, 0
+	}
 	co, w, h := POCNormaliseCoords(coords)
+	cells := make([]int64, len(co)/2)
+	for i := 0; i < len(co); i = i + 2 {
+		cells[i/2] = co[i+1]*w + co[i] // Row major so the sort is by y then x
+	}
+	sort.Slice(cells, func(i, j int) bool { return cells[i] < cells[j] })
+
 	var enc strings.Builder
-	for y := 0; y < int(h); y++ {
-		countOn := 0
-		countOff := 0
-		for x := 0; x < int(w); x++ {
-			found := false
-			for i := 0; i < len(co); i = i + 2 {
-				if x == int(co[i]) && y == int(co[i+1]) {
-					found = true
-					break
-				}
-			}
-			if found {
-				countOff = rleEncodeAppend(&enc, countOff, false)
-				countOn++
-			} else {
-				countOn = rleEncodeAppend(&enc, countOn, true)
-				countOff++
-			}
+	var y, x int64 = 0, 0 // Position of the next cell to be written
+	countOn := 0
+	for i, c := range cells {
+		if i > 0 && c == cells[i-1] {
+			continue // Duplicate cell
+		}
+		cy := c / w
+		cx := c % w
+		if countOn > 0 && (cy != y || cx != x) {
+			rleEncodeAppend(&enc, countOn, 'o')
+			countOn = 0
 		}
-		if y < int(h-1) {
-			rleEncodeAppend(&enc, countOff, false)
-			rleEncodeAppend(&enc, countOn, true)
-			enc.WriteString("$")
-		} else {
-			rleEncodeAppend(&enc, countOn, true)
-			enc.WriteString("!")
+		if cy != y {
+			rleEncodeAppend(&enc, int(cy-y), '$')
+			y = cy
+			x = 0
+		}
+		if cx != x {
+			rleEncodeAppend(&enc, int(cx-x), 'b')
 		}
+		countOn++
+		x = cx + 1
 	}
+	rleEncodeAppend(&enc, countOn, 'o')
+	enc.WriteString("!")
 	return enc.String(), w, h
-
 }
 
-func rleEncodeAppend(enc *strings.Builder, n int, on bool) int {
+func rleEncodeAppend(enc *strings.Builder, n int, tag byte) {
 	if n > 0 {
-		c := "b"
-		if on {
-			c = "o"
+		if n > 1 {
+			enc.WriteString(strconv.Itoa(n))
 		}
-		if n == 1 {
-			enc.WriteString(c)
-		} else {
-			enc.WriteString(fmt.Sprintf("%d%s", n, c))
+		enc.WriteByte(tag)
+	}
+}
+
+// Split the encoded cells in to lines no longer than width.
+// Lines are only split between items so a run count is never separated from its tag.
+func RLEWrapLines(enc string, width int) string {
+	var sb strings.Builder
+	lineLen := 0
+	itemStart := 0
+	for i := 0; i < len(enc); i++ {
+		c := enc[i]
+		if c >= '0' && c <= '9' && i < len(enc)-1 {
+			continue
+		}
+		item := enc[itemStart : i+1]
+		if lineLen > 0 && lineLen+len(item) > width {
+			sb.WriteString("\n")
+			lineLen = 0
 		}
+		sb.WriteString(item)
+		lineLen = lineLen + len(item)
+		itemStart = i + 1
 	}
-	return 0
+	return sb.String()
 }
 
 func (rle *RLE) String() string {
@@ -259,7 +467,7 @@ func (rle *RLE) String() string {
 		sb.WriteString(fmt.Sprintf("%3d, %3d ", rle.coords[i], rle.coords[i+1]))
 	}
 	sb.WriteString("\n")
-	sb.WriteString(fmt.Sprintf("%s\n", rle.decoded))
+	sb.WriteString(fmt.Sprintf("%s\n", rle.Decoded()))
 	return sb.String()
 }
 
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"os"
+)
+
+type GifExportOptions struct {
+	ImageExportOptions
+	Generations  int   // Number of frames. The first frame is the current generation
+	Delay        int   // Delay between frames in 100ths of a second
+	AutoViewport bool  // Size the view to fit all of the cells in all of the generations
+	X1, Y1       int64 // Fixed view (top left cell) if AutoViewport is false
+	X2, Y2       int64 // Fixed view (bottom right cell) if AutoViewport is false
+}
+
+func NewGifExportOptions() *GifExportOptions {
+	return &GifExportOptions{ImageExportOptions: *NewImageExportOptions(), Generations: 50, Delay: 10, AutoViewport: true}
+}
+
+type gifFrame struct {
+	coords []int64
+	modes  []int
+}
+
+// Write an animated GIF of the pattern.
+// A copy of the LifeGen is run so the original is not changed.
+func ExportGIF(w io.Writer, lg *LifeGen, opts *GifExportOptions) error {
+	if opts.Generations < 1 {
+		return fmt.Errorf("number of generations %d must be 1 or more", opts.Generations)
+	}
+	if opts.Delay < 0 {
+		return fmt.Errorf("frame delay %d must not be negative", opts.Delay)
+	}
+	run := lg.Copy()
+	frames := make([]*gifFrame, 0, opts.Generations)
+	var x1, y1, x2, y2 int64 = 0, 0, -1, -1
+	for i := 0; i < opts.Generations; i++ {
+		if i > 0 {
+			run.NextGen()
+		}
+		coords, modes := lifeGenCellsAndModes(run)
+		frames = append(frames, &gifFrame{coords: coords, modes: modes})
+		if opts.AutoViewport && len(coords) > 0 {
+			fx1, fy1, fx2, fy2 := exportBounds(coords)
+			if x2 < x1 {
+				x1, y1, x2, y2 = fx1, fy1, fx2, fy2
+			} else {
+				x1, y1, x2, y2 = minInt64(x1, fx1), minInt64(y1, fy1), maxInt64(x2, fx2), maxInt64(y2, fy2)
+			}
+		}
+	}
+	if !opts.AutoViewport {
+		x1, y1, x2, y2 = opts.X1, opts.Y1, opts.X2, opts.Y2
+		if x2 < x1 || y2 < y1 {
+			return fmt.Errorf("view %d,%d to %d,%d is empty", x1, y1, x2, y2)
+		}
+	}
+	ev, err := newExportView(x1, y1, x2, y2, &opts.ImageExportOptions)
+	if err != nil {
+		return err
+	}
+	palette, index := gifPalette(opts.Scheme)
+	anim := &gif.GIF{LoopCount: 0}
+	for _, f := range frames {
+		img := image.NewPaletted(image.Rect(0, 0, ev.pixW, ev.pixH), palette)
+		ev.draw(f.coords, f.modes, &opts.ImageExportOptions, func(r image.Rectangle, c color.RGBA) {
+			ci := index[c]
+			r = r.Intersect(img.Rect)
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				row := img.Pix[y*img.Stride : y*img.Stride+r.Max.X]
+				for x := r.Min.X; x < r.Max.X; x++ {
+					row[x] = ci
+				}
+			}
+		})
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, opts.Delay)
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// Create a palette from the colour scheme and a map from each colour to its palette index
+func gifPalette(scheme *ExportScheme) (color.Palette, map[color.RGBA]uint8) {
+	palette := color.Palette{}
+	index := make(map[color.RGBA]uint8)
+	for _, c := range append([]color.RGBA{scheme.Background, scheme.Grid}, scheme.Cells...) {
+		if _, found := index[c]; !found {
+			index[c] = uint8(len(palette))
+			palette = append(palette, c)
+		}
+	}
+	return palette, index
+}
+
+// Run the LifeGen and write an animated GIF file.
+func ExportLifeGenGIF(fileName string, lg *LifeGen, opts *GifExportOptions) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return ExportGIF(file, lg, opts)
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func absInt64(a int64) int64 {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"image/gif"
+	"strings"
+	"testing"
+)
+
+func TestExportGIF(t *testing.T) {
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	lg.AddCellsAtOffset(0, 0, 0, []int64{1, 0, 2, 1, 0, 2, 1, 2, 2, 2}) // Glider
+	opts := NewGifExportOptions()
+	opts.Generations = 8
+	opts.Delay = 25
+	opts.CellSize = 2
+	opts.Border = 0
+	var buf bytes.Buffer
+	err := ExportGIF(&buf, lg, opts)
+	if err != nil {
+		t.Errorf("ExportGIF: failed %s", err.Error())
+		return
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Errorf("ExportGIF: decode failed %s", err.Error())
+		return
+	}
+	if len(anim.Image) != 8 {
+		t.Errorf("ExportGIF: Expected 8 frames actual %d", len(anim.Image))
+	}
+	if anim.Delay[0] != 25 {
+		t.Errorf("ExportGIF: Expected delay 25 actual %d", anim.Delay[0])
+	}
+	// The glider moves 2 cells in 8 generations so the view is 5x5 cells
+	b := anim.Image[0].Bounds()
+	if b.Dx() != 10 || b.Dy() != 10 {
+		t.Errorf("ExportGIF: Expected size 10x10 actual %dx%d", b.Dx(), b.Dy())
+	}
+	assertColour(t, "Frame 0 cell", anim.Image[0].At(2, 0), opts.Scheme.Cells[0])
+	assertColour(t, "Frame 0 background", anim.Image[0].At(0, 0), opts.Scheme.Background)
+	if lg.GetGenerationCount() != 0 || lg.CountCells() != 5 {
+		t.Errorf("ExportGIF: The original LifeGen was changed")
+	}
+
+	opts.AutoViewport = false
+	opts.X1, opts.Y1, opts.X2, opts.Y2 = -10, -10, 9, 9
+	buf.Reset()
+	ExportGIF(&buf, lg, opts)
+	anim, _ = gif.DecodeAll(&buf)
+	b = anim.Image[0].Bounds()
+	if b.Dx() != 40 || b.Dy() != 40 {
+		t.Errorf("ExportGIF: Expected fixed size 40x40 actual %dx%d", b.Dx(), b.Dy())
+	}
+
+	opts.X2 = -20
+	err = ExportGIF(&buf, lg, opts)
+	if err == nil || !strings.Contains(err.Error(), "is empty") {
+		t.Errorf("ExportGIF: Expected an error for an empty view")
+	}
+	opts.Generations = 0
+	err = ExportGIF(&buf, lg, opts)
+	if err == nil || !strings.Contains(err.Error(), "must be 1 or more") {
+		t.Errorf("ExportGIF: Expected an error for 0 generations")
+	}
+}
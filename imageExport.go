@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	EXPORT_MAX_PIXELS = 16384 // Largest width or height of an exported image
+)
+
+// Colours used when exporting an image.
+// Cells is indexed by the cell mode (mode & COLOUR_MODE_MASK) wrapping round if shorter.
+type ExportScheme struct {
+	Name       string
+	Background color.RGBA
+	Grid       color.RGBA
+	Cells      []color.RGBA
+}
+
+var EXPORT_SCHEMES = []*ExportScheme{
+	{Name: "light", Background: color.RGBA{255, 255, 255, 255}, Grid: color.RGBA{200, 200, 200, 255}, Cells: []color.RGBA{{0, 0, 0, 255}}},
+	{Name: "dark", Background: color.RGBA{0, 0, 0, 255}, Grid: color.RGBA{60, 60, 60, 255}, Cells: []color.RGBA{{255, 255, 255, 255}}},
+	{Name: "life", Background: color.RGBA{0, 0, 0, 255}, Grid: color.RGBA{40, 40, 80, 255}, Cells: []color.RGBA{FC_CELL, FC_SELECT, FC_FULL, FC_EMPTY}},
+}
+
+type ImageExportOptions struct {
+	CellSize int           // Width and height of a cell in pixels
+	Border   int           // Number of empty cells around the pattern
+	Grid     bool          // Draw a 1 pixel grid line around each cell
+	Merge    bool          // SVG only. Write one rectangle for each run of cells in a row
+	Scheme   *ExportScheme // Colours. See EXPORT_SCHEMES
+}
+
+func NewImageExportOptions() *ImageExportOptions {
+	return &ImageExportOptions{CellSize: 6, Border: 1, Grid: false, Merge: true, Scheme: EXPORT_SCHEMES[0]}
+}
+
+// Find a colour scheme by name. Returns nil if not found
+func FindExportScheme(name string) *ExportScheme {
+	for _, s := range EXPORT_SCHEMES {
+		if strings.EqualFold(s.Name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+func ExportSchemeNames() []string {
+	names := make([]string, len(EXPORT_SCHEMES))
+	for i, s := range EXPORT_SCHEMES {
+		names[i] = s.Name
+	}
+	return names
+}
+
+func (s *ExportScheme) cellColour(mode int) color.RGBA {
+	return s.Cells[(mode&COLOUR_MODE_MASK)%len(s.Cells)]
+}
+
+// The area of the universe to be drawn and the size of the image.
+type exportView struct {
+	x1, y1        int64 // Cell at the top left of the image (including the border)
+	width, height int64 // In cells including the border
+	pixW, pixH    int   // In pixels
+	cs            int   // Cell size in pixels
+	ofs           int   // 1 if there is a grid line to the left of and above each cell
+}
+
+// Define the view from the min and max cell positions. The border is added to each side.
+func newExportView(x1, y1, x2, y2 int64, opts *ImageExportOptions) (*exportView, error) {
+	if opts.CellSize < 1 {
+		return nil, fmt.Errorf("cell size %d must be 1 or more", opts.CellSize)
+	}
+	if opts.Scheme == nil || len(opts.Scheme.Cells) == 0 {
+		return nil, fmt.Errorf("no colour scheme defined")
+	}
+	b := int64(opts.Border)
+	ev := &exportView{x1: x1 - b, y1: y1 - b, width: (x2 - x1) + 1 + b*2, height: (y2 - y1) + 1 + b*2, cs: opts.CellSize, ofs: 0}
+	if opts.Grid {
+		ev.ofs = 1
+	}
+	if ev.width*int64(ev.cs)+int64(ev.ofs) > EXPORT_MAX_PIXELS || ev.height*int64(ev.cs)+int64(ev.ofs) > EXPORT_MAX_PIXELS {
+		return nil, fmt.Errorf("image of %dx%d cells at cell size %d is larger than %d pixels", ev.width, ev.height, ev.cs, EXPORT_MAX_PIXELS)
+	}
+	ev.pixW = int(ev.width)*ev.cs + ev.ofs
+	ev.pixH = int(ev.height)*ev.cs + ev.ofs
+	return ev, nil
+}
+
+// Define the view so it contains all of the cells.
+func newExportViewForCells(coords []int64, opts *ImageExportOptions) (*exportView, error) {
+	x1, y1, x2, y2 := exportBounds(coords)
+	return newExportView(x1, y1, x2, y2, opts)
+}
+
+// The min and max cell positions. An empty list returns 0,0,-1,-1 (a view with no cells).
+func exportBounds(coords []int64) (int64, int64, int64, int64) {
+	if len(coords) == 0 {
+		return 0, 0, -1, -1
+	}
+	x1, y1 := coords[0], coords[1]
+	x2, y2 := x1, y1
+	for i := 2; i < len(coords); i = i + 2 {
+		if coords[i] < x1 {
+			x1 = coords[i]
+		}
+		if coords[i] > x2 {
+			x2 = coords[i]
+		}
+		if coords[i+1] < y1 {
+			y1 = coords[i+1]
+		}
+		if coords[i+1] > y2 {
+			y2 = coords[i+1]
+		}
+	}
+	return x1, y1, x2, y2
+}
+
+// Cell position in the view. Returns false if the cell is outside the view
+func (ev *exportView) cell(x, y int64) (int64, int64, bool) {
+	vx := x - ev.x1
+	vy := y - ev.y1
+	return vx, vy, vx >= 0 && vy >= 0 && vx < ev.width && vy < ev.height
+}
+
+// Draw the background, grid and cells using the fill function.
+// Cells outside the view are not drawn.
+func (ev *exportView) draw(coords []int64, modes []int, opts *ImageExportOptions, fill func(image.Rectangle, color.RGBA)) {
+	fill(image.Rect(0, 0, ev.pixW, ev.pixH), opts.Scheme.Background)
+	if opts.Grid {
+		for x := 0; x <= int(ev.width); x++ {
+			fill(image.Rect(x*ev.cs, 0, x*ev.cs+1, ev.pixH), opts.Scheme.Grid)
+		}
+		for y := 0; y <= int(ev.height); y++ {
+			fill(image.Rect(0, y*ev.cs, ev.pixW, y*ev.cs+1), opts.Scheme.Grid)
+		}
+	}
+	for i := 0; i < len(coords); i = i + 2 {
+		vx, vy, ok := ev.cell(coords[i], coords[i+1])
+		if ok {
+			px := int(vx)*ev.cs + ev.ofs
+			py := int(vy)*ev.cs + ev.ofs
+			fill(image.Rect(px, py, px+ev.cs-ev.ofs, py+ev.cs-ev.ofs), opts.Scheme.cellColour(exportMode(modes, i/2)))
+		}
+	}
+}
+
+func exportMode(modes []int, i int) int {
+	if modes == nil || i >= len(modes) {
+		return 0
+	}
+	return modes[i]
+}
+
+// Write the cells as a PNG image
+// modes can be nil in which case all cells are drawn with the first colour in the scheme
+func ExportPNG(w io.Writer, coords []int64, modes []int, opts *ImageExportOptions) error {
+	img, err := ExportImage(coords, modes, opts)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// Draw the cells in to an image.
+func ExportImage(coords []int64, modes []int, opts *ImageExportOptions) (*image.RGBA, error) {
+	ev, err := newExportViewForCells(coords, opts)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, ev.pixW, ev.pixH))
+	ev.draw(coords, modes, opts, func(r image.Rectangle, c color.RGBA) {
+		draw.Draw(img, r, &image.Uniform{c}, image.Point{}, draw.Src)
+	})
+	return img, nil
+}
+
+// Write the cells as an SVG image.
+// If opts.Merge is true each horizontal run of cells with the same mode is a single rectangle.
+func ExportSVG(w io.Writer, coords []int64, modes []int, opts *ImageExportOptions) error {
+	ev, err := newExportViewForCells(coords, opts)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" shape-rendering=\"crispEdges\">\n", ev.pixW, ev.pixH, ev.pixW, ev.pixH))
+	sb.WriteString(fmt.Sprintf("<rect width=\"%d\" height=\"%d\" fill=\"%s\"/>\n", ev.pixW, ev.pixH, svgColour(opts.Scheme.Background)))
+	if opts.Grid {
+		sb.WriteString(fmt.Sprintf("<path stroke=\"%s\" stroke-width=\"1\" d=\"", svgColour(opts.Scheme.Grid)))
+		for x := 0; x <= int(ev.width); x++ {
+			sb.WriteString(fmt.Sprintf("M%d.5 0V%d", x*ev.cs, ev.pixH))
+		}
+		for y := 0; y <= int(ev.height); y++ {
+			sb.WriteString(fmt.Sprintf("M0 %d.5H%d", y*ev.cs, ev.pixW))
+		}
+		sb.WriteString("\"/>\n")
+	}
+	// Sort the cells by mode, row then column so runs can be merged.
+	order := make([]int, len(coords)/2)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		ma, mb := exportMode(modes, a)&COLOUR_MODE_MASK, exportMode(modes, b)&COLOUR_MODE_MASK
+		if ma != mb {
+			return ma < mb
+		}
+		if coords[a*2+1] != coords[b*2+1] {
+			return coords[a*2+1] < coords[b*2+1]
+		}
+		return coords[a*2] < coords[b*2]
+	})
+	mode := -1
+	for i := 0; i < len(order); i++ {
+		c := order[i]
+		m := exportMode(modes, c) & COLOUR_MODE_MASK
+		if m != mode {
+			if mode >= 0 {
+				sb.WriteString("</g>\n")
+			}
+			sb.WriteString(fmt.Sprintf("<g fill=\"%s\">\n", svgColour(opts.Scheme.cellColour(m))))
+			mode = m
+		}
+		x, y, _ := ev.cell(coords[c*2], coords[c*2+1])
+		run := int64(1)
+		if opts.Merge {
+			for i+1 < len(order) {
+				n := order[i+1]
+				nx, ny, _ := ev.cell(coords[n*2], coords[n*2+1])
+				if exportMode(modes, n)&COLOUR_MODE_MASK != m || ny != y || nx > x+run {
+					break
+				}
+				run = nx - x + 1
+				i++
+			}
+		}
+		sb.WriteString(fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\"/>\n", int(x)*ev.cs+ev.ofs, int(y)*ev.cs+ev.ofs, int(run)*ev.cs-ev.ofs, ev.cs-ev.ofs))
+	}
+	if mode >= 0 {
+		sb.WriteString("</g>\n")
+	}
+	sb.WriteString("</svg>\n")
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+func svgColour(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// List the cells and their modes in the current generation
+func lifeGenCellsAndModes(lg *LifeGen) ([]int64, []int) {
+	coords := make([]int64, 0)
+	modes := make([]int, 0)
+	lg.VisitAllCells(func(lc *LifeCell) bool {
+		coords = append(coords, lc.x, lc.y)
+		modes = append(modes, lc.mode)
+		return true
+	})
+	return coords, modes
+}
+
+// Export the current generation to a .png or .svg file. The file extension defines the format.
+func ExportLifeGenImage(fileName string, lg *LifeGen, opts *ImageExportOptions) error {
+	coords, modes := lifeGenCellsAndModes(lg)
+	return ExportCellsImage(fileName, coords, modes, opts)
+}
+
+// Export a list of cells to a .png or .svg file. The file extension defines the format.
+// modes can be nil.
+func ExportCellsImage(fileName string, coords []int64, modes []int, opts *ImageExportOptions) error {
+	ext := strings.ToLower(path.Ext(fileName))
+	if ext != ".png" && ext != ".svg" {
+		return fmt.Errorf("cannot export to '%s'. The file name must end with .png or .svg", fileName)
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if ext == ".svg" {
+		err = ExportSVG(file, coords, modes, opts)
+	} else {
+		err = ExportPNG(file, coords, modes, opts)
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Do not leave a partly written image behind
+		os.Remove(fileName)
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestExportPNG(t *testing.T) {
+	opts := NewImageExportOptions()
+	opts.CellSize = 4
+	opts.Border = 1
+	var buf bytes.Buffer
+	err := ExportPNG(&buf, []int64{10, 10, 11, 10, 12, 10}, nil, opts)
+	if err != nil {
+		t.Errorf("ExportPNG: failed %s", err.Error())
+		return
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Errorf("ExportPNG: decode failed %s", err.Error())
+		return
+	}
+	b := img.Bounds()
+	if b.Dx() != 20 || b.Dy() != 12 {
+		t.Errorf("ExportPNG: Expected size 20x12 actual %dx%d", b.Dx(), b.Dy())
+	}
+	assertColour(t, "Border", img.At(1, 1), opts.Scheme.Background)
+	assertColour(t, "Cell 1", img.At(5, 5), opts.Scheme.Cells[0])
+	assertColour(t, "Cell 3", img.At(15, 7), opts.Scheme.Cells[0])
+	assertColour(t, "Right border", img.At(17, 5), opts.Scheme.Background)
+
+	opts.Grid = true
+	opts.Scheme = FindExportScheme("life")
+	img2, err := ExportImage([]int64{0, 0, 1, 0}, []int{0, SELECT_MODE_MASK}, opts)
+	if err != nil {
+		t.Errorf("ExportImage: failed %s", err.Error())
+		return
+	}
+	if img2.Bounds().Dx() != 17 || img2.Bounds().Dy() != 13 {
+		t.Errorf("ExportImage: Expected size 17x13 actual %dx%d", img2.Bounds().Dx(), img2.Bounds().Dy())
+	}
+	assertColour(t, "Grid", img2.At(4, 6), opts.Scheme.Grid)
+	assertColour(t, "Mode 0", img2.At(6, 6), FC_CELL)
+	assertColour(t, "Mode 1", img2.At(10, 6), FC_SELECT)
+}
+
+func TestExportSVG(t *testing.T) {
+	opts := NewImageExportOptions()
+	opts.Border = 0
+	coords := []int64{0, 0, 1, 0, 2, 0, 4, 0, 0, 2}
+	var buf bytes.Buffer
+	err := ExportSVG(&buf, coords, nil, opts)
+	if err != nil {
+		t.Errorf("ExportSVG: failed %s", err.Error())
+		return
+	}
+	s := buf.String()
+	if !strings.HasPrefix(s, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"30\" height=\"18\"") {
+		t.Errorf("ExportSVG: Incorrect header %s", s)
+	}
+	// Background + 3 merged runs
+	if strings.Count(s, "<rect") != 4 {
+		t.Errorf("ExportSVG: Expected 4 rect elements. Actual %d\n%s", strings.Count(s, "<rect"), s)
+	}
+	if !strings.Contains(s, "<rect x=\"0\" y=\"0\" width=\"18\" height=\"6\"/>") {
+		t.Errorf("ExportSVG: Expected a merged rect of 3 cells\n%s", s)
+	}
+	opts.Merge = false
+	buf.Reset()
+	ExportSVG(&buf, coords, nil, opts)
+	if strings.Count(buf.String(), "<rect") != 6 {
+		t.Errorf("ExportSVG: Expected 6 rect elements. Actual %d", strings.Count(buf.String(), "<rect"))
+	}
+}
+
+func TestExportFile(t *testing.T) {
+	rle, err := NewRleFile("testdata/GliderGun.rle")
+	if err != nil {
+		t.Errorf("RLE File load failed. %e", err)
+	}
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	lg.AddCellsAtOffset(0, 0, 0, rle.coords)
+	dir := t.TempDir()
+	for _, fn := range []string{"gun.png", "gun.svg"} {
+		err = ExportLifeGenImage(path.Join(dir, fn), lg, NewImageExportOptions())
+		if err != nil {
+			t.Errorf("ExportLifeGenImage: %s failed %s", fn, err.Error())
+		}
+		st, err := os.Stat(path.Join(dir, fn))
+		if err != nil || st.Size() == 0 {
+			t.Errorf("ExportLifeGenImage: %s was not written", fn)
+		}
+	}
+	err = ExportLifeGenImage(path.Join(dir, "gun.jpg"), lg, NewImageExportOptions())
+	if err == nil || !strings.Contains(err.Error(), "must end with .png or .svg") {
+		t.Errorf("ExportLifeGenImage: Expected an error for .jpg")
+	}
+	opts := NewImageExportOptions()
+	opts.CellSize = 1000
+	err = ExportLifeGenImage(path.Join(dir, "big.png"), lg, opts)
+	if err == nil || !strings.Contains(err.Error(), "is larger than") {
+		t.Errorf("ExportLifeGenImage: Expected an error for a very large image")
+	}
+	if _, err := os.Stat(path.Join(dir, "big.png")); !os.IsNotExist(err) {
+		t.Errorf("ExportLifeGenImage: The failed big.png should have been removed")
+	}
+}
+
+func assertColour(t *testing.T, id string, act color.Color, exp color.RGBA) {
+	r, g, b, a := act.RGBA()
+	er, eg, eb, ea := exp.RGBA()
+	if r != er || g != eg || b != eb || a != ea {
+		t.Errorf("%s: Expected colour %v actual %v", id, exp, act)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2"
+)
+
+const (
+	LAUNCH_MIN_DELAY = 10  // Same limits as the Life faster and slower buttons
+	LAUNCH_MAX_DELAY = 400 //
+	LAUNCH_MIN_SIZE  = 200
+)
+
+// A demo or application that can be run in the main window
+type LaunchApp struct {
+	name  string
+	title string
+	main  func(fyne.Window, float64, float64, *MoverController) *fyne.Container
+	close func() error // Called after the window is closed. Can be nil
+}
+
+var launchApps = []*LaunchApp{
+	{name: "life", title: "Game of Life", main: MainPOCLife, close: POCLifeClose},
+	{name: "movers", title: "Movers demo", main: MainPOC},
+	{name: "lots", title: "Lots of movers", main: MainPOCLots},
+}
+
+type LaunchOptions struct {
+	App     *LaunchApp // nil to show the startup chooser
+	Width   float64
+	Height  float64
+	Pattern string    // The pattern file loaded when Life starts
+	Delay   int64     // Animation delay in milliseconds. 0 is the default for the app
+	Rule    *LifeRule // The rule used by Life
+	Prefs   string    // The Life preferences file. Empty for the default file
+	given   map[string]bool
+}
+
+// Return true if the flag was given on the command line. Values given override the preferences
+func (opts *LaunchOptions) Given(flag string) bool {
+	return opts != nil && opts.given[flag]
+}
+
+func FindLaunchApp(name string) *LaunchApp {
+	for _, a := range launchApps {
+		if a.name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// Return true if the arguments start a window rather than run a command.
+// No arguments, options only or the name of an app.
+func IsLaunchCommand(args []string) bool {
+	return len(args) == 0 || strings.HasPrefix(args[0], "-") || FindLaunchApp(args[0]) != nil
+}
+
+/*
+grtest [life|movers|lots] -width 1000 -height 1000 -pattern file.rle -delay 100 -rule B3/S23 -prefs file.json
+*/
+func ParseLaunchArgs(args []string, stderr io.Writer) (*LaunchOptions, error) {
+	opts := &LaunchOptions{given: make(map[string]bool)}
+	name := "grtest"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		opts.App = FindLaunchApp(args[0])
+		if opts.App == nil {
+			return nil, fmt.Errorf("unknown app '%s'. Use one of %s", args[0], strings.Join(launchAppNames(), ","))
+		}
+		name = args[0]
+		args = args[1:]
+	}
+	fs := cliFlagSet(name, stderr)
+	fs.Float64Var(&opts.Width, "width", 1000, "The width of the window")
+	fs.Float64Var(&opts.Height, "height", 1000, "The height of the window")
+	fs.StringVar(&opts.Pattern, "pattern", lifeStartFile, "The pattern file loaded when Life starts")
+	fs.Int64Var(&opts.Delay, "delay", 0, fmt.Sprintf("Animation delay in milliseconds (%d..%d). Default is the app default", LAUNCH_MIN_DELAY, LAUNCH_MAX_DELAY))
+	rule := fs.String("rule", LIFE_RULE_CONWAY, "The Life rule in B/S notation")
+	fs.StringVar(&opts.Prefs, "prefs", "", fmt.Sprintf("The Life preferences file. Default is %s", LifePrefsFileName()))
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		opts.given[f.Name] = true
+	})
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument '%s'", fs.Arg(0))
+	}
+	if opts.Width < LAUNCH_MIN_SIZE || opts.Height < LAUNCH_MIN_SIZE {
+		return nil, fmt.Errorf("width and height must be at least %d", LAUNCH_MIN_SIZE)
+	}
+	if opts.Delay != 0 && (opts.Delay < LAUNCH_MIN_DELAY || opts.Delay > LAUNCH_MAX_DELAY) {
+		return nil, fmt.Errorf("delay %d must be between %d and %d", opts.Delay, LAUNCH_MIN_DELAY, LAUNCH_MAX_DELAY)
+	}
+	// The default pattern is relative to the repo. If it is missing Life shows the error when it starts
+	if opts.Given("pattern") {
+		if _, err := os.Stat(opts.Pattern); err != nil {
+			return nil, fmt.Errorf("pattern file '%s' not found", opts.Pattern)
+		}
+		if !IsPatternFile(opts.Pattern) {
+			return nil, fmt.Errorf("file '%s' is not a pattern file. Use one of %s", opts.Pattern, strings.Join(PatternFormatNames(), ","))
+		}
+	}
+	r, err := ParseLifeRule(*rule)
+	if err != nil {
+		return nil, err
+	}
+	opts.Rule = r
+	return opts, nil
+}
+
+func launchAppNames() []string {
+	resp := make([]string, len(launchApps))
+	for i, a := range launchApps {
+		resp[i] = a.name
+	}
+	return resp
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLaunchArgs(t *testing.T) {
+	if !IsLaunchCommand([]string{}) || !IsLaunchCommand([]string{"-width", "500"}) || !IsLaunchCommand([]string{"life"}) || IsLaunchCommand([]string{"run"}) {
+		t.Errorf("IsLaunchCommand returned the wrong result")
+	}
+	opts := AssertLaunchArgs(t, []string{})
+	if opts.App != nil || opts.Width != 1000 || opts.Height != 1000 || opts.Delay != 0 || opts.Pattern != lifeStartFile || !opts.Rule.IsConway() {
+		t.Errorf("default options are wrong %+v", opts)
+	}
+	opts = AssertLaunchArgs(t, []string{"life", "-width", "800", "-height", "600", "-pattern", "testdata/blinker.rle", "-delay", "50", "-rule", "23/36"})
+	if opts.App.name != "life" || opts.Width != 800 || opts.Height != 600 || opts.Delay != 50 || opts.Pattern != "testdata/blinker.rle" || opts.Rule.String() != "B36/S23" {
+		t.Errorf("life options are wrong %+v", opts)
+	}
+	if !opts.Given("delay") || !opts.Given("pattern") || opts.Given("prefs") {
+		t.Errorf("life options given are wrong %v", opts.given)
+	}
+	opts = AssertLaunchArgs(t, []string{"-delay", "20", "-prefs", "my-prefs.json"})
+	if opts.App != nil || opts.Delay != 20 || opts.Prefs != "my-prefs.json" || opts.Given("pattern") {
+		t.Errorf("chooser options are wrong %+v", opts)
+	}
+	AssertLaunchArgsError(t, []string{"walk"}, "unknown app 'walk'. Use one of life,movers,lots")
+	AssertLaunchArgsError(t, []string{"lots", "extra"}, "unexpected argument 'extra'")
+	AssertLaunchArgsError(t, []string{"lots", "-width", "100"}, "width and height must be at least 200")
+	AssertLaunchArgsError(t, []string{"life", "-delay", "5"}, "delay 5 must be between 10 and 400")
+	AssertLaunchArgsError(t, []string{"life", "-pattern", "testdata/missing.rle"}, "pattern file 'testdata/missing.rle' not found")
+	AssertLaunchArgsError(t, []string{"life", "-pattern", "testdata/times.txt"}, "file 'testdata/times.txt' is not a pattern file. Use one of rle,cells,life106")
+	AssertLaunchArgsError(t, []string{"life", "-rule", "B3"}, "rule 'B3' must be in the form B3/S23")
+}
+
+func TestLaunchArgsOutsideRepo(t *testing.T) {
+	wd, _ := os.Getwd()
+	pattern := filepath.Join(wd, "testdata", "blinker.rle")
+	os.Chdir(t.TempDir())
+	defer os.Chdir(wd)
+	// The default pattern is not found but is only checked when it is given
+	for _, args := range [][]string{{}, {"movers"}, {"lots"}, {"life"}} {
+		if opts := AssertLaunchArgs(t, args); opts.Pattern != lifeStartFile {
+			t.Errorf("launch args %v pattern is wrong %s", args, opts.Pattern)
+		}
+	}
+	AssertLaunchArgs(t, []string{"life", "-pattern", pattern})
+	AssertLaunchArgsError(t, []string{"life", "-pattern", lifeStartFile}, "pattern file 'testdata/Infinite_growth.rle' not found")
+}
+
+func AssertLaunchArgs(t *testing.T, args []string) *LaunchOptions {
+	var stderr bytes.Buffer
+	opts, err := ParseLaunchArgs(args, &stderr)
+	if err != nil {
+		t.Fatalf("launch args %v failed %s", args, err.Error())
+	}
+	return opts
+}
+
+func AssertLaunchArgsError(t *testing.T, args []string, exp string) {
+	var stderr bytes.Buffer
+	_, err := ParseLaunchArgs(args, &stderr)
+	if err == nil {
+		t.Errorf("launch args %v should fail with '%s'", args, exp)
+		return
+	}
+	if err.Error() != exp {
+		t.Errorf("launch args %v error '%s' expected '%s'", args, err.Error(), exp)
+	}
+}
@@ -3,13 +3,17 @@ package main
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 	"time"
 )
 
 const (
-	indexMult    = 100000000
-	RUN_FOR_EVER = math.MaxInt
+	indexMult        = 100000000
+	RUN_FOR_EVER     = math.MaxInt
+	REMOVE_MODE_MASK = 0b10000000      // Marks cells to be removed by RemoveCells. Not used for anything else
+	LIFE_CELL_MAX    = indexMult/2 - 2 // The largest x or y. Beyond this y would collide with the next column in ind. Allows for neighbours
+	LIFE_SKIP_STEP   = 64              // Cells between the skip pointers used by CellsInBounds
 )
 
 type LifeGenId int
@@ -29,6 +33,11 @@ type LifeCell struct {
 	next *LifeCell
 }
 
+// Return true if a cell at x,y has a unique ind. See LIFE_CELL_MAX
+func LifeCellInRange(x, y int64) bool {
+	return x >= -LIFE_CELL_MAX && x <= LIFE_CELL_MAX && y >= -LIFE_CELL_MAX && y <= LIFE_CELL_MAX
+}
+
 func (lc *LifeCell) Clone() *LifeCell {
 	return &LifeCell{x: lc.x, y: lc.y, ind: lc.ind, mode: lc.mode, next: nil}
 }
@@ -41,6 +50,7 @@ type LifeDeadCells struct {
 type LifeGen struct {
 	generations     []*LifeCell      // The root cell in the linked list of cells
 	cellIndex       []*LifeCell      // The cell in the middle of the list according to its ind
+	cellSkip        [][]*LifeCell    // Every LIFE_SKIP_STEP cell in ind order. Built by CellsInBounds. nil when the list changes
 	cellCount       []int            // The number of cells in the list after NextGen is called
 	currentGenId    LifeGenId        // The current generation (index to generations,cellIndex and cellCount)
 	countGen        int              // The number of generations since the cells were loaded
@@ -49,6 +59,9 @@ type LifeGen struct {
 	runFor          int              // Count down for generations
 	startTimeMillis int64            // Time in milli seconds for the start of NextGen
 	timeMillis      int64            // The time in milli seconds that NextGen took
+	rule            *LifeRule        // The rule used by NextGen. Default is B3/S23
+	births          int              // Cells born by the last NextGen
+	deaths          int              // Cells that died in the last NextGen
 }
 
 const (
@@ -57,7 +70,7 @@ const (
 )
 
 func NewLifeGen(genDone func(*LifeGen), runFor int) *LifeGen {
-	lg := &LifeGen{generations: make([]*LifeCell, 2), cellIndex: make([]*LifeCell, 2), cellCount: make([]int, 2), onGenDone: genDone, onGenStopped: nil}
+	lg := &LifeGen{generations: make([]*LifeCell, 2), cellIndex: make([]*LifeCell, 2), cellSkip: make([][]*LifeCell, 2), cellCount: make([]int, 2), onGenDone: genDone, onGenStopped: nil, rule: NewConwayRule()}
 	lg.Reset()
 	lg.SetRunFor(runFor, nil)
 	return lg
@@ -69,6 +82,14 @@ func (lg *LifeGen) SetRunFor(n int, f func(*LifeGen)) {
 	lg.onGenStopped = f
 }
 
+func (lg *LifeGen) SetRule(rule *LifeRule) {
+	lg.rule = rule
+}
+
+func (lg *LifeGen) GetRule() *LifeRule {
+	return lg.rule
+}
+
 func (lg *LifeGen) GetRunFor() int {
 	return lg.runFor
 }
@@ -83,6 +104,16 @@ func (lg *LifeGen) GetGenerationCount() int {
 	return lg.countGen
 }
 
+// Set the generation count. Used by undo to go back to the generation before a run
+func (lg *LifeGen) SetGenerationCount(n int) {
+	lg.countGen = n
+}
+
+// The number of cells born and the number that died in the last generation
+func (lg *LifeGen) GetBirthsAndDeaths() (int, int) {
+	return lg.births, lg.deaths
+}
+
 // Time taken for the last call to NextGen in milliseconds
 func (lg *LifeGen) GetGenerationTime() int64 {
 	return lg.timeMillis
@@ -95,6 +126,8 @@ func (lg *LifeGen) IsRunning() bool {
 func (lg *LifeGen) Reset() {
 	lg.cellIndex[LIFE_GEN_1] = nil
 	lg.cellIndex[LIFE_GEN_2] = nil
+	lg.cellSkip[LIFE_GEN_1] = nil
+	lg.cellSkip[LIFE_GEN_2] = nil
 	lg.generations[LIFE_GEN_1] = nil
 	lg.generations[LIFE_GEN_2] = nil
 	lg.cellCount[LIFE_GEN_1] = 0
@@ -104,6 +137,42 @@ func (lg *LifeGen) Reset() {
 	lg.runFor = 0
 	lg.startTimeMillis = 0
 	lg.timeMillis = 0
+	lg.births = 0
+	lg.deaths = 0
+}
+
+// Create a new LifeGen with a copy of the cells in the current generation.
+// The copy has the same rule, no callbacks and will run for ever. The generation count starts at 0.
+func (lg *LifeGen) Copy() *LifeGen {
+	cp := NewLifeGen(nil, RUN_FOR_EVER)
+	cp.rule = lg.rule
+	var prev *LifeCell = nil
+	count := 0
+	lg.VisitAllCells(func(lc *LifeCell) bool {
+		c := lc.Clone()
+		if prev == nil {
+			cp.generations[cp.currentGenId] = c
+		} else {
+			prev.next = c
+		}
+		prev = c
+		count++
+		return true
+	})
+	cp.cellCount[cp.currentGenId] = count
+	return cp
+}
+
+// Replace the cells and the rule with those of from and add gens to the generation count. from is not changed.
+// Must not be called while running. See IsRunning
+func (lg *LifeGen) Replace(from *LifeGen, gens int) {
+	cp := from.Copy()
+	countGen := lg.countGen + gens
+	lg.Reset()
+	lg.generations[lg.currentGenId] = cp.generations[cp.currentGenId]
+	lg.cellCount[lg.currentGenId] = cp.cellCount[cp.currentGenId]
+	lg.countGen = countGen
+	lg.rule = cp.rule
 }
 
 func (lg *LifeGen) ClearMode(mode int) {
@@ -118,12 +187,18 @@ func (lg *LifeGen) GetRootCell() *LifeCell {
 	return lg.generations[lg.currentGenId]
 }
 
+// Call found for each cell inside X1,Y1 to X2,Y2 (inclusive).
+// The cells are sorted by x so the scan starts near X1,Y1 (see skipTo) and stops at the first cell to the right of X2.
+// The time taken does not depend on the number of cells to the left of X1.
 func (lg *LifeGen) CellsInBounds(X1, Y1, X2, Y2 int64, found func(*LifeCell)) {
 	if found == nil {
 		return
 	}
-	cell := lg.GetRootCell()
+	cell := lg.skipTo(X1, Y1)
 	for cell != nil {
+		if cell.x > X2 {
+			return
+		}
 		if cell.x >= X1 && cell.x <= X2 && cell.y >= Y1 && cell.y <= Y2 {
 			found(cell)
 		}
@@ -131,12 +206,89 @@ func (lg *LifeGen) CellsInBounds(X1, Y1, X2, Y2 int64, found func(*LifeCell)) {
 	}
 }
 
+// Return the last skip pointer before cell x,y or the root cell.
+// The skip pointers are built on the first call after the list has changed so a view that is
+// drawn many times while stopped only scans the list once.
+func (lg *LifeGen) skipTo(x, y int64) *LifeCell {
+	root := lg.GetRootCell()
+	if root == nil {
+		return nil
+	}
+	skip := lg.cellSkip[lg.currentGenId]
+	if skip == nil {
+		skip = make([]*LifeCell, 0, lg.cellCount[lg.currentGenId]/LIFE_SKIP_STEP+1)
+		n := 0
+		for c := root; c != nil; c = c.next {
+			if n%LIFE_SKIP_STEP == 0 {
+				skip = append(skip, c)
+			}
+			n++
+		}
+		lg.cellSkip[lg.currentGenId] = skip
+	}
+	// Keep x and y in range so ind does not overflow or fall in to another column
+	if x < -LIFE_CELL_MAX {
+		return root
+	}
+	if x > LIFE_CELL_MAX {
+		x = LIFE_CELL_MAX + 1
+	}
+	if y < -indexMult/2 {
+		y = -indexMult / 2
+	}
+	if y > indexMult/2 {
+		y = indexMult / 2
+	}
+	ind := x*indexMult + y
+	i := sort.Search(len(skip), func(i int) bool {
+		return skip[i].ind >= ind
+	})
+	if i == 0 {
+		return root
+	}
+	return skip[i-1]
+}
+
 // Scan the current generation and produce the next generation.
 // Then swap generations so the next gen becomes the current gen
 func (lg *LifeGen) NextGen() {
 	if lg.runFor <= 0 {
 		return
 	}
+	lg.step()
+	//
+	// Call the function requested at the end of the Generation process
+	// This is NOT included in the timing as it may involve GUI stuff
+	// If is run as a separate thread so it will not block the generation processing
+	//
+	if lg.onGenDone != nil {
+		go lg.onGenDone(lg)
+	}
+	// Run N (runFor) generations then Stop.
+	// Use the callback (onGenStopped) to notify the controller when stopped.
+	// See RUN_FOR_EVER.
+	// Once called the onGenStopped will need to be set again. It is only called ONCE.
+	//
+	lg.runFor = lg.runFor - 1
+	if lg.runFor <= 0 {
+		if lg.onGenStopped != nil {
+			f := lg.onGenStopped
+			lg.onGenStopped = nil
+			f(lg)
+		}
+	}
+}
+
+// Run n generations now. runFor is not used or changed and the callbacks are not called.
+// Used to run a LifeGen that is not being animated, for example a copy used by a script.
+func (lg *LifeGen) Step(n int) {
+	for i := 0; i < n; i++ {
+		lg.step()
+	}
+}
+
+// Calculate the next generation
+func (lg *LifeGen) step() {
 	//
 	// If startTimeMillis is not 0 then we a concurrently calling NextGen before it is finished!
 	//
@@ -157,6 +309,7 @@ func (lg *LifeGen) NextGen() {
 	// for later processing.
 	//
 	cn := 0
+	before := 0
 	var xc int64 = 0
 	var yc int64 = 0
 	current := lg.generations[lg.currentGenId]
@@ -166,14 +319,15 @@ func (lg *LifeGen) NextGen() {
 		cn = lg.countNear(xc, yc, deadCells)
 		//
 		// Number of surrounding live cells
-		// 		2 Means the cell continues in next gen
-		// 		3 Means the cell is new in next gen
+		// 		For B3/S23 2 or 3 means the cell continues in next gen
 		//
-		if cn == 2 || cn == 3 {
+		if lg.rule.Survives(cn) {
 			count = count + lg.addCellToGen(xc, yc, current.mode, gen2)
 		}
+		before++
 		current = current.next
 	}
+	survivors := count
 	//
 	// Now we have a list of all the surrounding dead cells we need to see if they are alive in next gen
 	//
@@ -181,11 +335,11 @@ func (lg *LifeGen) NextGen() {
 	for dc != nil {
 		xc = dc.x
 		yc = dc.y
-		cn = lg.countNearFast(xc, yc)
+		cn = lg.countNearFast(xc, yc, lg.rule.maxBorn)
 		//
-		// If a dead cell position has 3 live surrounding cells it is alive in the nex generation
+		// If a dead cell position has 3 (for B3/S23) live surrounding cells it is alive in the nex generation
 		//
-		if cn == 3 {
+		if lg.rule.Born(cn) {
 			count = count + lg.addCellToGen(xc, yc, dc.mode, gen2)
 		}
 		dc = dc.next
@@ -195,58 +349,40 @@ func (lg *LifeGen) NextGen() {
 	lg.countGen = lg.countGen + 1
 	// Set the cell count
 	lg.cellCount[gen2] = count
+	lg.births = count - survivors
+	lg.deaths = before - survivors
 
 	// Swap generations and clear the next gen and next gen cell count
 	lg.currentGenId = gen2
 	lg.generations[gen1] = nil
+	lg.cellSkip[gen1] = nil
 	lg.cellCount[gen1] = 0
 
 	// time the process and clear the start time
 	lg.timeMillis = time.Now().UnixMilli() - lg.startTimeMillis
 	lg.startTimeMillis = 0
-	//
-	// Call the function requested at the end of the Generation process
-	// This is NOT included in the timing as it may involve GUI stuff
-	// If is run as a separate thread so it will not block the generation processing
-	//
-	if lg.onGenDone != nil {
-		go lg.onGenDone(lg)
-	}
-	// Run N (runFor) generations then Stop.
-	// Use the callback (onGenStopped) to notify the controller when stopped.
-	// See RUN_FOR_EVER.
-	// Once called the onGenStopped will need to be set again. It is only called ONCE.
-	//
-	lg.runFor = lg.runFor - 1
-	if lg.runFor <= 0 {
-		if lg.onGenStopped != nil {
-			f := lg.onGenStopped
-			lg.onGenStopped = nil
-			f(lg)
-		}
-	}
 }
 
 // Count cells around a dead cell to see if it will be live in the next gen
-// Only need to count up to 4 so finish early if count > 3
-func (lg *LifeGen) countNearFast(x, y int64) int {
+// Only need to count up to max + 1 so finish early if count > max (for B3/S23 max is 3)
+func (lg *LifeGen) countNearFast(x, y int64, max int) int {
 	count := lg.GetCell(x-1, y-1)
 	count = count + lg.GetCell(x-1, y)
 	count = count + lg.GetCell(x-1, y+1)
 	count = count + lg.GetCell(x, y-1)
-	if count > 3 {
+	if count > max {
 		return count
 	}
 	count = count + lg.GetCell(x, y+1)
-	if count > 3 {
+	if count > max {
 		return count
 	}
 	count = count + lg.GetCell(x+1, y-1)
-	if count > 3 {
+	if count > max {
 		return count
 	}
 	count = count + lg.GetCell(x+1, y)
-	if count > 3 {
+	if count > max {
 		return count
 	}
 	count = count + lg.GetCell(x+1, y+1)
@@ -395,6 +531,48 @@ func (lg *LifeGen) AddCellsAtOffset(x, y int64, mode int, c []int64) int {
 	return n
 }
 
+// Replace all of the cells with x,y pairs. The cells are sorted once instead of inserted one at a time so this is
+// quicker than AddCellsAtOffset for many cells. Duplicates are ignored. The generation count is not changed.
+func (lg *LifeGen) SetCells(c []int64) {
+	cells := make([]*LifeCell, 0, len(c)/2)
+	for i := 0; i+1 < len(c); i = i + 2 {
+		cells = append(cells, &LifeCell{x: c[i], y: c[i+1], ind: c[i]*indexMult + c[i+1], mode: 0})
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		return cells[i].ind < cells[j].ind
+	})
+	var root, prev *LifeCell
+	count := 0
+	for _, lc := range cells {
+		if prev != nil && prev.ind == lc.ind {
+			continue
+		}
+		if prev == nil {
+			root = lc
+		} else {
+			prev.next = lc
+		}
+		prev = lc
+		count++
+	}
+	lg.generations[lg.currentGenId] = root
+	lg.cellIndex[lg.currentGenId] = nil
+	lg.cellSkip[lg.currentGenId] = nil
+	lg.cellCount[lg.currentGenId] = count
+}
+
+// As AddCellsAtOffset but returns the x,y pairs (including the offset) that were added. Cells that already exist are not returned.
+func (lg *LifeGen) AddNewCells(x, y int64, mode int, c []int64) []int64 {
+	added := make([]int64, 0)
+	for i := 0; i < len(c); i = i + 2 {
+		if lg.addCellToGen(x+c[i], y+c[i+1], mode, lg.currentGenId) > 0 {
+			added = append(added, x+c[i], y+c[i+1])
+		}
+	}
+	lg.cellCount[lg.currentGenId] = lg.cellCount[lg.currentGenId] + len(added)/2
+	return added
+}
+
 func (lg *LifeGen) ListCellsWithMode(mask int) []int64 {
 	resp := make([]int64, 0)
 	lg.VisitAllCells(func(lc *LifeCell) bool {
@@ -407,11 +585,17 @@ func (lg *LifeGen) ListCellsWithMode(mask int) []int64 {
 	return resp
 }
 
-func (lg *LifeGen) RemoveCellsWithMode(mask int) {
+// Remove the cells with any of the bits in mask. Returns the x,y pairs removed
+func (lg *LifeGen) RemoveCellsWithMode(mask int) []int64 {
 	var root *LifeCell = nil
 	var prev *LifeCell = nil
+	count := 0
+	removed := make([]int64, 0)
 	lg.VisitAllCells(func(lc *LifeCell) bool {
-		if (lc.mode & mask) == 0 { // If mask not matched then Keep the cell
+		if (lc.mode & mask) != 0 {
+			removed = append(removed, lc.x, lc.y)
+		} else { // If mask not matched then Keep the cell
+			count++
 			if root == nil {
 				root = lc.Clone()
 				prev = root
@@ -423,6 +607,10 @@ func (lg *LifeGen) RemoveCellsWithMode(mask int) {
 		return true
 	})
 	lg.generations[lg.currentGenId] = root
+	lg.cellIndex[lg.currentGenId] = nil
+	lg.cellSkip[lg.currentGenId] = nil
+	lg.cellCount[lg.currentGenId] = count
+	return removed
 }
 
 func (lg *LifeGen) CountCells() int {
@@ -461,12 +649,32 @@ func (lg *LifeGen) VisitAllCells(callback func(*LifeCell) bool) bool {
 	return true
 }
 
+// Remove a list of x,y pairs in one pass of the cells. Cells that do not exist are ignored.
+// Returns the x,y pairs removed
+func (lg *LifeGen) RemoveCells(c []int64) []int64 {
+	if len(c) == 0 {
+		return nil
+	}
+	set := make(map[[2]int64]bool, len(c)/2)
+	for i := 0; i < len(c); i = i + 2 {
+		set[[2]int64{c[i], c[i+1]}] = true
+	}
+	lg.VisitAllCells(func(lc *LifeCell) bool {
+		if set[[2]int64{lc.x, lc.y}] {
+			lc.mode = lc.mode | REMOVE_MODE_MASK
+		}
+		return true
+	})
+	return lg.RemoveCellsWithMode(REMOVE_MODE_MASK)
+}
+
 // Remove a single cell.
 func (lg *LifeGen) RemoveCell(x, y int64) {
 	c := lg.generations[lg.currentGenId]
 	if c == nil {
 		return
 	}
+	lg.cellSkip[lg.currentGenId] = nil
 	if c.x == x && c.y == y {
 		lg.generations[lg.currentGenId] = c.next
 		return
@@ -491,6 +699,7 @@ func (lg *LifeGen) RemoveCell(x, y int64) {
 //			The last cess has the highes ind value
 func (lg *LifeGen) addCellToGen(x, y int64, mode int, genId LifeGenId) int {
 	lg.cellIndex[genId] = nil
+	lg.cellSkip[genId] = nil
 	toAdd := &LifeCell{x: x, y: y, next: nil, ind: x*indexMult + y, mode: mode}
 	toAddid := toAdd.ind
 	if lg.generations[genId] == nil { // Generation has NO cells so cell becomes the root cell
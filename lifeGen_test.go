@@ -108,6 +108,151 @@ func TestLifeRemoveCells(t *testing.T) {
 		t.Errorf("ibeacon: Remove all except first 3 Expected count:%d actual count:%d", 3, n)
 	}
 }
+func TestLifeCopy(t *testing.T) {
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	lg.AddCellsAtOffset(0, 0, SELECT_MODE_MASK, []int64{0, 0, 1, 0, 2, 0})
+	lg.NextGen()
+	cp := lg.Copy()
+	testGen(t, cp, "Copy:", "1,-1 1,0 1,1")
+	if cp.GetCellCount() != 3 || cp.CountCellsWithMode(SELECT_MODE_MASK) != 1 {
+		t.Errorf("Copy: Expected 3 cells, 1 with mode 1. Actual %d, %d", cp.GetCellCount(), cp.CountCellsWithMode(SELECT_MODE_MASK))
+	}
+	cp.NextGen()
+	testGen(t, cp, "Copy NextGen:", "0,0 1,0 2,0")
+	testGen(t, lg, "Original:", "1,-1 1,0 1,1")
+}
+
+func TestLifeCellsInBounds(t *testing.T) {
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	coords := make([]int64, 0)
+	for x := int64(-90); x <= 90; x = x + 3 {
+		for y := int64(-90); y <= 90; y = y + 7 {
+			coords = append(coords, x, y)
+		}
+	}
+	lg.AddCellsAtOffset(0, 0, 0, coords)
+	rects := [][]int64{{-100, -100, 100, 100}, {0, 0, 10, 10}, {-31, 5, -29, 60}, {89, -90, 200, 90}, {-1000, -1000, -91, 1000}, {-indexMult, -indexMult, indexMult, indexMult}}
+	testCellsInBounds(t, lg, "Loaded", rects)
+	// The skip pointers must not be used after the cells change
+	lg.RemoveCell(-30, 6)
+	lg.AddCell(-30, 7, 0)
+	testCellsInBounds(t, lg, "RemoveCell and AddCell", rects)
+	lg.RemoveCells([]int64{0, 1, 3, 8})
+	testCellsInBounds(t, lg, "RemoveCells", rects)
+	lg.NextGen()
+	testCellsInBounds(t, lg, "NextGen", rects)
+}
+
+func testCellsInBounds(t *testing.T, lg *LifeGen, id string, rects [][]int64) {
+	for _, r := range rects {
+		exp := make([]int64, 0)
+		lg.VisitAllCells(func(lc *LifeCell) bool {
+			if lc.x >= r[0] && lc.x <= r[2] && lc.y >= r[1] && lc.y <= r[3] {
+				exp = append(exp, lc.x, lc.y)
+			}
+			return true
+		})
+		got := make([]int64, 0)
+		lg.CellsInBounds(r[0], r[1], r[2], r[3], func(lc *LifeCell) {
+			got = append(got, lc.x, lc.y)
+		})
+		if fmt.Sprint(got) != fmt.Sprint(exp) {
+			t.Errorf("%s: CellsInBounds %v returned %d cells expected %d", id, r, len(got)/2, len(exp)/2)
+		}
+	}
+}
+
+// The view has 100 cells with 200000 cells to the left of it. See CellsInBounds
+func BenchmarkLifeCellsInBoundsOffScreen(b *testing.B) {
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	coords := make([]int64, 0)
+	// Added in reverse order so each cell is added at the root
+	for x := int64(9); x >= -2000; x-- {
+		ys := int64(100)
+		if x >= 0 {
+			ys = 10
+		}
+		for y := ys - 1; y >= 0; y-- {
+			coords = append(coords, x, y)
+		}
+	}
+	lg.AddCellsAtOffset(0, 0, 0, coords)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := 0
+		lg.CellsInBounds(0, 0, 100, 100, func(lc *LifeCell) {
+			n++
+		})
+		if n != 100 {
+			b.Fatalf("Expected 100 cells in the view. Actual %d", n)
+		}
+	}
+}
+
+func TestLifeSetCells(t *testing.T) {
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	lg.AddCellsAtOffset(0, 0, 0, []int64{9, 9})
+	lg.SetCells([]int64{2, 0, 0, 0, 1, 0, 2, 0, 1, -1})
+	testGen(t, lg, "SetCells:", "0,0 1,-1 1,0 2,0")
+	if lg.GetCellCount() != 4 || lg.GetCell(1, -1) != 1 {
+		t.Errorf("SetCells: Expected 4 cells including 1,-1. Actual %d", lg.GetCellCount())
+	}
+	lg.SetCells(nil)
+	testGen(t, lg, "SetCells nil:", "None")
+}
+
+func TestLifeStepAndReplace(t *testing.T) {
+	stopped := false
+	lg := NewLifeGen(nil, 0)
+	lg.SetRunFor(0, func(*LifeGen) { stopped = true })
+	lg.AddCellsAtOffset(0, 0, SELECT_MODE_MASK, []int64{0, 0, 1, 0, 2, 0})
+	// Step does not need or change runFor
+	lg.Step(3)
+	testGen(t, lg, "Step:", "1,-1 1,0 1,1")
+	if lg.GetGenerationCount() != 3 || lg.GetRunFor() != 0 || lg.IsRunning() || stopped {
+		t.Errorf("Step: Expected 3 generations and not running. Actual %d, %d", lg.GetGenerationCount(), lg.GetRunFor())
+	}
+	run := lg.Copy()
+	highLife, _ := ParseLifeRule("B36/S23")
+	run.SetRule(highLife)
+	run.Step(1)
+	run.AddCell(5, 5, 0)
+	lg.Replace(run, run.GetGenerationCount())
+	testGen(t, lg, "Replace:", "0,0 1,0 2,0 5,5")
+	if lg.GetGenerationCount() != 4 || lg.GetRule() != highLife || lg.GetCellCount() != 4 || lg.CountCellsWithMode(SELECT_MODE_MASK) != run.CountCellsWithMode(SELECT_MODE_MASK) {
+		t.Errorf("Replace: Expected generation 4, HighLife and 4 cells with the same modes. Actual %d %s %d", lg.GetGenerationCount(), lg.GetRule().String(), lg.GetCellCount())
+	}
+	// The cells are copied
+	run.Step(1)
+	testGen(t, lg, "Replace then step the original:", "0,0 1,0 2,0 5,5")
+}
+
+func TestLifeTransformCells(t *testing.T) {
+	lg := NewLifeGen(nil, 0)
+	lg.AddCellsAtOffset(0, 0, SELECT_MODE_MASK, []int64{0, 0, 1, 0, 2, 0})
+	lg.AddCell(10, 10, 0)
+	if n := lg.TransformCellsWithMode(SELECT_MODE_MASK, SYM_ROT_90); n != 3 {
+		t.Errorf("Rotate: Expected 3 cells moved. Actual %d", n)
+	}
+	testGen(t, lg, "Rotate:", "1,-1 1,0 1,1 10,10")
+	if lg.GetCellCount() != 4 || lg.CountCellsWithMode(SELECT_MODE_MASK) != 3 {
+		t.Errorf("Rotate: Expected 4 cells, 3 selected. Actual %d, %d", lg.GetCellCount(), lg.CountCellsWithMode(SELECT_MODE_MASK))
+	}
+	lg.MoveCellsWithMode(SELECT_MODE_MASK, 2, 3)
+	testGen(t, lg, "Move:", "3,2 3,3 3,4 10,10")
+	if lg.GetCellCount() != 4 || lg.GetCell(3, 3) == 0 || lg.GetCell(1, 0) != 0 {
+		t.Errorf("Move: Expected 4 cells with 3,3 and not 1,0. Actual %d", lg.GetCellCount())
+	}
+
+	lg.Reset()
+	lg.AddCellsAtOffset(5, 5, SELECT_MODE_MASK, []int64{0, 0, 1, 0, 0, 1})
+	lg.TransformCellsWithMode(SELECT_MODE_MASK, SYM_FLIP_X)
+	testGen(t, lg, "Flip:", "5,5 6,5 6,6")
+	if n := lg.TransformCellsWithMode(SELECT_MODE_MASK<<1, SYM_FLIP_X); n != 0 {
+		t.Errorf("Flip: Expected 0 cells moved with no cells matching the mask. Actual %d", n)
+	}
+}
+
 func TestLifeVisitAllCells(t *testing.T) {
 	rle, err := NewRleFile("testdata/ibeacon.rle")
 	if err != nil {
@@ -242,8 +387,8 @@ func TestLifeRLE(t *testing.T) {
 	assertStr(t, "David Buckingham", rle.owner)
 	assertStr(t, "testdata/rats.rle", rle.fileName)
 	assertStr(t, "A period 6 oscillator found in 1972.", rle.comment)
-	if len(rle.decoded) != 286 {
-		t.Errorf("TestRle: Expected len(decoded):%d actual len(decoded):%d", 64, len(rle.decoded))
+	if len(rle.Decoded()) != 286 {
+		t.Errorf("TestRle: Expected len(decoded):%d actual len(decoded):%d", 64, len(rle.Decoded()))
 	}
 	if len(rle.coords) != 64 {
 		t.Errorf("TestRle: Expected len(coords):%d actual len(coords):%d", 64, len(rle.coords))
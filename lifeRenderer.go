@@ -0,0 +1,156 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+)
+
+/*
+Draws the visible part of a LifeGen in to a single image shown by a canvas.Raster.
+
+	Draw is called after each generation. It draws in to a back buffer and then swaps it with the front buffer.
+	The raster generator (called by Fyne when it paints) returns the front buffer.
+	Only the cells in the view are drawn so the time to draw does not depend on the cells that are off screen.
+*/
+type LifeRenderer struct {
+	mu      sync.Mutex
+	raster  *canvas.Raster
+	front   *image.RGBA
+	back    *image.RGBA
+	pixW    int // Size in pixels requested by the raster. 0 until the first paint
+	pixH    int
+	Round   bool         // Draw cells as circles. Squares are used if the cells are smaller than 3 pixels
+	Colours []color.RGBA // Indexed by mode & COLOUR_MODE_MASK
+	mask    []bool       // Pixels inside a round cell of size maskFor
+	maskFor int
+}
+
+// The area of the universe shown and the size of a cell.
+//
+//	X, Y is the cell at the top left. CellSize is in pixels and can be less than 1 (many cells per pixel).
+type LifeView struct {
+	X, Y     float64
+	CellSize float64
+}
+
+func NewLifeRenderer() *LifeRenderer {
+	lr := &LifeRenderer{Colours: make([]color.RGBA, len(COLOURS))}
+	for i, c := range COLOURS {
+		r, g, b, a := c.RGBA()
+		lr.Colours[i] = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	}
+	lr.raster = canvas.NewRaster(func(w, h int) image.Image {
+		lr.mu.Lock()
+		defer lr.mu.Unlock()
+		lr.pixW, lr.pixH = w, h
+		if lr.front == nil {
+			return image.NewRGBA(image.Rect(0, 0, w, h))
+		}
+		return lr.front
+	})
+	return lr
+}
+
+func (lr *LifeRenderer) Raster() *canvas.Raster {
+	return lr.raster
+}
+
+/*
+Draw the cells for the view. view.CellSize is in Fyne units and is scaled to pixels.
+size is the size of the raster in Fyne units.
+*/
+func (lr *LifeRenderer) Draw(lg *LifeGen, view LifeView, size fyne.Size) {
+	lr.mu.Lock()
+	w, h := lr.pixW, lr.pixH
+	lr.mu.Unlock()
+	if w == 0 || h == 0 {
+		w, h = int(size.Width), int(size.Height)
+	}
+	scale := 1.0
+	if size.Width > 0 {
+		scale = float64(w) / float64(size.Width)
+	}
+	if lr.back == nil || lr.back.Rect.Dx() != w || lr.back.Rect.Dy() != h {
+		lr.back = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+	lr.RenderImage(lr.back, lg, LifeView{X: view.X, Y: view.Y, CellSize: view.CellSize * scale})
+	lr.mu.Lock()
+	lr.front, lr.back = lr.back, lr.front
+	lr.mu.Unlock()
+	lr.raster.Refresh()
+}
+
+// Clear the image and draw the cells that are inside it
+func (lr *LifeRenderer) RenderImage(img *image.RGBA, lg *LifeGen, view LifeView) {
+	for i := range img.Pix {
+		img.Pix[i] = 0
+	}
+	if view.CellSize <= 0 {
+		return
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	x1 := int64(math.Floor(view.X))
+	y1 := int64(math.Floor(view.Y))
+	x2 := int64(math.Ceil(view.X + float64(w)/view.CellSize))
+	y2 := int64(math.Ceil(view.Y + float64(h)/view.CellSize))
+	cs := int(math.Ceil(view.CellSize))
+	round := lr.Round && cs >= 3
+	if round && lr.maskFor != cs {
+		lr.mask = lifeRoundMask(cs)
+		lr.maskFor = cs
+	}
+	lg.CellsInBounds(x1, y1, x2, y2, func(lc *LifeCell) {
+		px := int(math.Floor((float64(lc.x) - view.X) * view.CellSize))
+		py := int(math.Floor((float64(lc.y) - view.Y) * view.CellSize))
+		c := lr.Colours[(lc.mode&COLOUR_MODE_MASK)%len(lr.Colours)]
+		if round {
+			lifeFillMask(img, px, py, cs, lr.mask, c)
+		} else {
+			lifeFillRect(img, px, py, cs, c)
+		}
+	})
+}
+
+// Fill a square of size cs with the top left at px, py. Pixels outside the image are skipped.
+func lifeFillRect(img *image.RGBA, px, py, cs int, c color.RGBA) {
+	r := image.Rect(px, py, px+cs, py+cs).Intersect(img.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := img.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+			i = i + 4
+		}
+	}
+}
+
+func lifeFillMask(img *image.RGBA, px, py, cs int, mask []bool, c color.RGBA) {
+	r := image.Rect(px, py, px+cs, py+cs).Intersect(img.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := img.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if mask[(y-py)*cs+(x-px)] {
+				img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+			}
+			i = i + 4
+		}
+	}
+}
+
+// The pixels of a cs x cs square that are inside a circle that fills it
+func lifeRoundMask(cs int) []bool {
+	mask := make([]bool, cs*cs)
+	r := float64(cs) / 2
+	for y := 0; y < cs; y++ {
+		for x := 0; x < cs; x++ {
+			dx := float64(x) + 0.5 - r
+			dy := float64(y) + 0.5 - r
+			mask[y*cs+x] = dx*dx+dy*dy <= r*r
+		}
+	}
+	return mask
+}
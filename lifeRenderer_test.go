@@ -0,0 +1,61 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestLifeRendererImage(t *testing.T) {
+	lg := NewLifeGen(nil, 0)
+	lg.AddCellsAtOffset(0, 0, 0, []int64{0, 0, 2, 1, 100, 100, -50, 3})
+	lg.AddCell(1, 1, SELECT_MODE_MASK)
+	lr := NewLifeRenderer()
+	cell := lr.Colours[0]
+	empty := color.RGBA{}
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+
+	lr.RenderImage(img, lg, LifeView{X: 0, Y: 0, CellSize: 10})
+	AssertPixel(t, img, 5, 5, cell, "cell 0,0")
+	AssertPixel(t, img, 25, 15, cell, "cell 2,1")
+	AssertPixel(t, img, 15, 15, lr.Colours[SELECT_MODE_MASK], "selected cell 1,1")
+	AssertPixel(t, img, 15, 5, empty, "no cell 1,0")
+	AssertPixelCount(t, img, 300)
+
+	// Move the view so 0,0 is half off the left edge
+	lr.RenderImage(img, lg, LifeView{X: 0.5, Y: 0, CellSize: 10})
+	AssertPixel(t, img, 0, 0, cell, "part of cell 0,0")
+	AssertPixel(t, img, 5, 0, empty, "after part of cell 0,0")
+	AssertPixelCount(t, img, 250)
+
+	// Round cells leave the corners empty
+	lr.Round = true
+	lr.RenderImage(img, lg, LifeView{X: 0, Y: 0, CellSize: 10})
+	AssertPixel(t, img, 0, 0, empty, "corner of round cell 0,0")
+	AssertPixel(t, img, 5, 5, cell, "centre of round cell 0,0")
+
+	// Zoomed out so several cells share a pixel. 100,100 is outside the image
+	lr.Round = false
+	lr.RenderImage(img, lg, LifeView{X: -60, Y: 0, CellSize: 0.25})
+	AssertPixel(t, img, 2, 0, cell, "cell -50,3")
+	AssertPixel(t, img, 15, 0, cell, "cells 0,0 to 2,1")
+	AssertPixelCount(t, img, 2)
+}
+
+func AssertPixel(t *testing.T, img *image.RGBA, x, y int, exp color.RGBA, desc string) {
+	if got := img.RGBAAt(x, y); got != exp {
+		t.Errorf("%s: pixel %d,%d is %v expected %v", desc, x, y, got, exp)
+	}
+}
+
+func AssertPixelCount(t *testing.T, img *image.RGBA, exp int) {
+	n := 0
+	for i := 3; i < len(img.Pix); i = i + 4 {
+		if img.Pix[i] != 0 {
+			n++
+		}
+	}
+	if n != exp {
+		t.Errorf("%d pixels were drawn expected %d", n, exp)
+	}
+}
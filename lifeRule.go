@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	LIFE_RULE_CONWAY = "B3/S23"
+)
+
+// A well known life-like rule
+type NamedLifeRule struct {
+	Name string
+	Rule string
+}
+
+// Offered by the rule dialog. All of them can be parsed by ParseLifeRule
+var NAMED_LIFE_RULES = []NamedLifeRule{
+	{"Life", LIFE_RULE_CONWAY},
+	{"HighLife", "B36/S23"},
+	{"Day & Night", "B3678/S34678"},
+	{"Seeds", "B2/S"},
+	{"Life without Death", "B3/S012345678"},
+	{"34 Life", "B34/S34"},
+	{"2x2", "B36/S125"},
+	{"Maze", "B3/S12345"},
+	{"Coral", "B3/S45678"},
+	{"Diamoeba", "B35678/S5678"},
+	{"Morley", "B368/S245"},
+	{"Anneal", "B4678/S35678"},
+	{"Replicator", "B1357/S1357"},
+}
+
+// A life-like rule. Bit n of born or survive is set if a cell with n neighbours is born or survives.
+//
+//	maxBorn is the largest neighbour count that causes a birth. Counting around a dead cell can stop above it.
+type LifeRule struct {
+	born    uint16
+	survive uint16
+	maxBorn int
+}
+
+// Parse a rule in B/S notation (B3/S23) or the older S/B notation (23/3).
+// B0 rules are not supported as every empty cell would be born.
+func ParseLifeRule(rule string) (*LifeRule, error) {
+	r := strings.ToUpper(strings.TrimSpace(rule))
+	parts := strings.Split(r, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("rule '%s' must be in the form B3/S23", rule)
+	}
+	var born, survive string
+	switch {
+	case strings.HasPrefix(parts[0], "B") && strings.HasPrefix(parts[1], "S"):
+		born, survive = parts[0][1:], parts[1][1:]
+	case strings.HasPrefix(parts[0], "S") && strings.HasPrefix(parts[1], "B"):
+		survive, born = parts[0][1:], parts[1][1:]
+	default:
+		survive, born = parts[0], parts[1] // S/B notation. For example 23/3
+	}
+	lr := &LifeRule{maxBorn: -1}
+	var err error
+	lr.born, err = parseRuleCounts(born, rule)
+	if err != nil {
+		return nil, err
+	}
+	lr.survive, err = parseRuleCounts(survive, rule)
+	if err != nil {
+		return nil, err
+	}
+	if lr.born&1 != 0 {
+		return nil, fmt.Errorf("rule '%s' is not supported. A cell cannot be born with 0 neighbours", rule)
+	}
+	for n := 8; n >= 0; n-- {
+		if lr.born&(1<<n) != 0 {
+			lr.maxBorn = n
+			break
+		}
+	}
+	return lr, nil
+}
+
+// The rule for Conway's Game of Life B3/S23
+func NewConwayRule() *LifeRule {
+	lr, _ := ParseLifeRule(LIFE_RULE_CONWAY)
+	return lr
+}
+
+func parseRuleCounts(counts, rule string) (uint16, error) {
+	var bits uint16 = 0
+	for _, c := range counts {
+		if c < '0' || c > '8' {
+			return 0, fmt.Errorf("rule '%s' has invalid neighbour count '%c'. Must be 0..8", rule, c)
+		}
+		bits = bits | 1<<(c-'0')
+	}
+	return bits, nil
+}
+
+// Return true if a dead cell with n neighbours is alive in the next generation
+func (lr *LifeRule) Born(n int) bool {
+	return lr.born&(1<<n) != 0
+}
+
+// Return true if a live cell with n neighbours is alive in the next generation
+func (lr *LifeRule) Survives(n int) bool {
+	return lr.survive&(1<<n) != 0
+}
+
+func (lr *LifeRule) IsConway() bool {
+	return lr.String() == LIFE_RULE_CONWAY
+}
+
+// The rule in B/S notation. For example B3/S23
+func (lr *LifeRule) String() string {
+	var sb strings.Builder
+	sb.WriteString("B")
+	for n := 0; n <= 8; n++ {
+		if lr.Born(n) {
+			sb.WriteByte(byte('0' + n))
+		}
+	}
+	sb.WriteString("/S")
+	for n := 0; n <= 8; n++ {
+		if lr.Survives(n) {
+			sb.WriteByte(byte('0' + n))
+		}
+	}
+	return sb.String()
+}
+
+// The name of the rule if it is one of NAMED_LIFE_RULES, otherwise an empty string
+func (lr *LifeRule) Name() string {
+	rs := lr.String()
+	for _, nr := range NAMED_LIFE_RULES {
+		if nr.Rule == rs {
+			return nr.Name
+		}
+	}
+	return ""
+}
+
+// The rule with its name if it has one. For example "B36/S23 (HighLife)"
+func (lr *LifeRule) Title() string {
+	if n := lr.Name(); n != "" {
+		return fmt.Sprintf("%s (%s)", lr.String(), n)
+	}
+	return lr.String()
+}
+
+// The names in NAMED_LIFE_RULES in order. Used for the rule selector.
+func LifeRuleNames() []string {
+	resp := make([]string, len(NAMED_LIFE_RULES))
+	for i, nr := range NAMED_LIFE_RULES {
+		resp[i] = nr.Name
+	}
+	return resp
+}
+
+// Find a rule by name (case is ignored) or by B/S notation
+func LifeRuleByName(name string) (*LifeRule, error) {
+	for _, nr := range NAMED_LIFE_RULES {
+		if strings.EqualFold(nr.Name, strings.TrimSpace(name)) {
+			return ParseLifeRule(nr.Rule)
+		}
+	}
+	return ParseLifeRule(name)
+}
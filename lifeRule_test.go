@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLifeRuleParse(t *testing.T) {
+	AssertLifeRule(t, "B3/S23", "B3/S23")
+	AssertLifeRule(t, "b3/s23", "B3/S23")
+	AssertLifeRule(t, "23/3", "B3/S23")
+	AssertLifeRule(t, "S23/B36", "B36/S23")
+	AssertLifeRule(t, " B63/S32 ", "B36/S23")
+	AssertLifeRule(t, "B2/S", "B2/S")
+	AssertLifeRuleError(t, "B3S23", "rule 'B3S23' must be in the form B3/S23")
+	AssertLifeRuleError(t, "B39/S23", "rule 'B39/S23' has invalid neighbour count '9'. Must be 0..8")
+	AssertLifeRuleError(t, "B3/S2x", "rule 'B3/S2x' has invalid neighbour count 'X'. Must be 0..8")
+	AssertLifeRuleError(t, "B03/S23", "rule 'B03/S23' is not supported. A cell cannot be born with 0 neighbours")
+
+	r := NewConwayRule()
+	if !r.IsConway() || r.maxBorn != 3 || !r.Born(3) || r.Born(2) || !r.Survives(2) || !r.Survives(3) || r.Survives(4) {
+		t.Errorf("conway rule is wrong %s", r.String())
+	}
+}
+
+func TestLifeRuleNextGen(t *testing.T) {
+	// The middle cell has 6 neighbours. It is only born with B36 and all other cells die
+	cells := []int64{0, 0, 1, 0, 2, 0, 0, 2, 1, 2, 2, 2}
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	lg.AddCellsAtOffset(0, 0, 0, cells)
+	lg.NextGen()
+	if lg.GetCell(1, 1) != 0 {
+		t.Errorf("B3/S23 the middle cell should not be born")
+	}
+	hl, _ := ParseLifeRule("B36/S23")
+	lg = NewLifeGen(nil, RUN_FOR_EVER)
+	lg.SetRule(hl)
+	lg.AddCellsAtOffset(0, 0, 0, cells)
+	lg.NextGen()
+	if lg.GetCell(1, 1) != 1 {
+		t.Errorf("B36/S23 the middle cell should be born")
+	}
+	if lg.Copy().GetRule() != hl {
+		t.Errorf("copy should keep the rule")
+	}
+}
+
+func TestLifeRuleNamed(t *testing.T) {
+	for _, nr := range NAMED_LIFE_RULES {
+		r, err := LifeRuleByName(nr.Name)
+		if err != nil {
+			t.Errorf("named rule %s '%s' failed %s", nr.Name, nr.Rule, err.Error())
+			continue
+		}
+		if r.String() != nr.Rule || r.Name() != nr.Name {
+			t.Errorf("named rule %s returned '%s' named '%s' expected '%s'", nr.Name, r.String(), r.Name(), nr.Rule)
+		}
+	}
+	if r, _ := LifeRuleByName(" day & NIGHT "); r == nil || r.Title() != "B3678/S34678 (Day & Night)" {
+		t.Errorf("named rule 'day & NIGHT' was not found")
+	}
+	if r, _ := LifeRuleByName("S23/B36"); r == nil || r.Title() != "B36/S23 (HighLife)" {
+		t.Errorf("rule 'S23/B36' should be HighLife")
+	}
+	if r, _ := LifeRuleByName("B35/S23"); r == nil || r.Name() != "" || r.Title() != "B35/S23" {
+		t.Errorf("rule 'B35/S23' should not have a name")
+	}
+	if _, err := LifeRuleByName("Lief"); err == nil {
+		t.Errorf("rule 'Lief' should fail")
+	}
+	if len(LifeRuleNames()) != len(NAMED_LIFE_RULES) || LifeRuleNames()[0] != "Life" {
+		t.Errorf("rule names are wrong %v", LifeRuleNames())
+	}
+}
+
+func TestCliRunRule(t *testing.T) {
+	// Blinker under B/S (nothing is born) dies
+	stdout := AssertCliCommand(t, []string{"run", "-in", "testdata/blinker.rle", "-gens", "1", "-rule", "B/S2"}, CLI_EXIT_OK)
+	if !bytes.Contains([]byte(stdout), []byte("Rule       : B/S2\nGenerations: 1\nPopulation : 1\n")) {
+		t.Errorf("run with rule output is wrong:\n%s", stdout)
+	}
+	AssertCliCommand(t, []string{"run", "-in", "testdata/blinker.rle", "-rule", "B9/S2"}, CLI_EXIT_ERROR)
+}
+
+func AssertLifeRule(t *testing.T, rule, exp string) {
+	r, err := ParseLifeRule(rule)
+	if err != nil {
+		t.Errorf("rule '%s' failed %s", rule, err.Error())
+		return
+	}
+	if r.String() != exp {
+		t.Errorf("rule '%s' returned '%s' expected '%s'", rule, r.String(), exp)
+	}
+}
+
+func AssertLifeRuleError(t *testing.T, rule, exp string) {
+	_, err := ParseLifeRule(rule)
+	if err == nil {
+		t.Errorf("rule '%s' should fail with '%s'", rule, exp)
+		return
+	}
+	if err.Error() != exp {
+		t.Errorf("rule '%s' error '%s' expected '%s'", rule, err.Error(), exp)
+	}
+}
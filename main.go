@@ -1,10 +1,14 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
 )
 
 var (
@@ -16,6 +20,15 @@ var (
 -------------------------------------------------------------------- main
 */
 func main() {
+	if !IsLaunchCommand(os.Args[1:]) {
+		os.Exit(RunCommand(os.Args[1:], os.Stdout, os.Stderr))
+	}
+	opts, err := ParseLaunchArgs(os.Args[1:], os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		cliUsage(os.Stderr)
+		os.Exit(CLI_EXIT_USAGE)
+	}
 	a := app.New()
 	mainWindow := a.NewWindow("Hello")
 	mainWindow.SetCloseIntercept(func() {
@@ -23,8 +36,54 @@ func main() {
 	})
 	mainWindow.SetMaster()
 	mainWindow.SetIcon(GoLogo_Png)
-	mainController = NewMoverController(1000, 1000)
-	mainContainer = MainPOCLots(mainWindow, 1000, 1000, mainController)
+	mainController = NewMoverController(opts.Width, opts.Height)
+	if opts.App == nil {
+		mainWindow.SetContent(launchChooser(mainWindow, opts))
+	} else {
+		launchApp(mainWindow, opts)
+	}
+	mainWindow.ShowAndRun()
+	mainController.StopAnimation()
+	if opts.App != nil && opts.App.close != nil {
+		if err := opts.App.close(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		}
+	}
+}
+
+/*
+Show buttons to choose which app to run
+*/
+func launchChooser(mainWindow fyne.Window, opts *LaunchOptions) fyne.CanvasObject {
+	mainWindow.SetTitle("grtest")
+	mainWindow.Resize(fyne.Size{Width: float32(opts.Width) / 2, Height: float32(opts.Height) / 2})
+	box := container.NewVBox(widget.NewLabel("Choose what to run:"))
+	for _, la := range launchApps {
+		chosen := la
+		box.Add(widget.NewButton(fmt.Sprintf("%s (%s)", chosen.title, chosen.name), func() {
+			opts.App = chosen
+			launchApp(mainWindow, opts)
+		}))
+	}
+	box.Add(widget.NewButton("Exit", func() {
+		mainWindow.Close()
+	}))
+	return container.NewCenter(box)
+}
+
+/*
+Run the chosen app in the main window
+*/
+func launchApp(mainWindow fyne.Window, opts *LaunchOptions) {
+	mainWindow.SetTitle(opts.App.title)
+	lifeStartFile = opts.Pattern
+	lifeRule = opts.Rule
+	lifeLaunch = opts
+	if opts.Delay > 0 {
+		currentDelay = opts.Delay
+		mainController.SetAnimationDelay(opts.Delay)
+	}
+	mainContainer = opts.App.main(mainWindow, opts.Width, opts.Height, mainController)
 	mainWindow.Canvas().SetOnTypedKey(func(key *fyne.KeyEvent) {
 		if key.Name == "Escape" {
 			mainWindow.Close()
@@ -42,7 +101,4 @@ func main() {
 		time.Sleep(time.Millisecond * 500)
 		mainController.InitAnimationController(mainController.GetAnimationDelay(), nil)
 	}()
-
-	mainWindow.ShowAndRun()
-	mainController.StopAnimation()
 }
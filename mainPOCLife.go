@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"image/color"
 	"io/fs"
+	"math"
+	"math/rand"
 	"os"
 	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -19,6 +24,10 @@ import (
 const (
 	SELECT_MODE_MASK = 0b00000001
 	COLOUR_MODE_MASK = 0b00000011
+
+	LIFE_GRID_MIN  = 1.0 / 16 // Smallest cell size. 16 cells per pixel
+	LIFE_GRID_MAX  = 100.0    // Largest cell size
+	LIFE_ZOOM_STEP = 1.25     // Zoom for + and - and for each click of the mouse wheel
 )
 
 var (
@@ -30,13 +39,34 @@ var (
 	lifeGenStopped  bool
 	selectedCellsXY []int64
 
-	dots             []*canvas.Circle = make([]*canvas.Circle, 0)
-	dotsPos          int              = 0
-	gridSize         int64            = 6
-	xOffset          int64            = 0
-	yOffset          int64            = 0
-	currentDelay     int64            = 100
+	lifeRenderer     *LifeRenderer
+	lifeMinimap      *LifeMinimap
+	lifeMinimapDrag  bool          // Dragging the view rectangle on the minimap
+	pasteRenderer    *LifeRenderer // Draws pasteGen (or toolGen) over the Life view while pasting (or drawing)
+	lifeUndo         = NewLifeUndo(UNDO_MAX)
+	lifeRunBefore    []int64  // The cells when the run started. nil if not running
+	lifeRunGen       int      // The generation when the run started
+	lifeTool         LifeTool // The tool used by the primary mouse button
+	lifeToolDrawing  bool     // A drag with a tool has started. See POCLifeToolPush
+	lifeToolAdded    []int64  // The cells added by the current drag with a tool
+	lifeToolRemoved  []int64  // The cells removed by the current drag with a tool
+	lifeToolLastX    int64    // The last cell painted by the pencil or eraser
+	lifeToolLastY    int64
+	lifeToolRand     = rand.New(rand.NewSource(time.Now().UnixNano()))
+	toolGen          *LifeGen                   // The shape being dragged by a tool. nil if none
+	pasteGen         *LifeGen                   // The cells being pasted with the top left at 0,0. nil if not pasting
+	gridSize         float64  = PREFS_GRID_SIZE // Size of a cell. Less than 1 if there are many cells per pixel
+	xOffset          float64  = 0               // Cells from the left of the view to cell 0,0
+	yOffset          float64  = 0
+	lifePanning      bool     // Dragging with the middle button or with space held down
+	lifePanX         float64  // xOffset when panning started
+	lifePanY         float64
+	lifeSpaceDown    bool
+	cursorCellX      int64 = 0
+	cursorCellY      int64 = 0
+	currentDelay     int64 = PREFS_DELAY
 	currentWd        string
+	currentSaveDir   string // The directory for save and export
 	stopButton       *widget.Button
 	startButton      *widget.Button
 	stepButton       *widget.Button
@@ -46,41 +76,98 @@ var (
 	fasterButton     *widget.Button
 	slowerButton     *widget.Button
 	saveContainer    *fyne.Container
+	saveRleForm      *widget.Form
+	exportForm       *widget.Form
+	apgContainer     *fyne.Container
+	ruleContainer    *fyne.Container
+	ruleButton       *widget.Button // Shows the current rule. Opens ruleContainer
+	findContainer    *fyne.Container
+	findList         *widget.List
+	findResults      []*PatternIndexEntry
+	lifeIndex        *PatternIndex // The index for currentWd. Replaced, not changed, when an update finishes. See POCLifeIndexUpdate
+	lifeIndexMu      sync.Mutex    // Guards lifeIndex
+	lifeIndexUpdate  sync.Mutex    // Held while an index is updated so only one update runs at a time
+	lifeFrameTasks   []func()      // Run by the frame loop before the next generation. See POCLifeOnFrame
+	lifeFrameMu      sync.Mutex    // Guards lifeFrameTasks
+	findIndex        *PatternIndex // The index that findResults came from
 	errorContainer   *ErrorContainer
 	ownerEntry       = widget.NewEntry()
 	descriptionEntry = widget.NewEntry()
+	apgEntry         = widget.NewEntry()
+	ruleEntry        = widget.NewEntry()
+	ruleNamed        = widget.NewSelect(LifeRuleNames(), nil)
+	findEntry        = widget.NewEntry()
+	findLabel        = widget.NewLabel("")
+	exportSizeEntry  = widget.NewEntry()
+	exportGensEntry  = widget.NewEntry()
+	exportScheme     = widget.NewSelect(ExportSchemeNames(), nil)
+	exportGrid       = widget.NewCheck("Grid", nil)
+	saveCanonical    = widget.NewCheck("Canonicalise (rotate, flip and move to the canonical form)", nil)
+	minimapCheck     = widget.NewCheck("Map (M)", nil)
+	toolSelect       = widget.NewSelect(LifeToolNames(), nil)
+	graphCheck       = widget.NewCheck("Graph (G)", nil)
+	graphSeries      *fyne.Container // Checks for the series shown in the graph. Only visible with the graph
+	lifeGraph        *PopulationGraph
+	toolDensity      = widget.NewSelect(TOOL_DENSITIES, nil)
 	timeText         = widget.NewLabel("")
 	targetDot        *canvas.Circle
 	targetRect       *canvas.Rectangle
 	rleFile          *RLE
 	rleError         error
+	lifeStartFile    = "testdata/Infinite_growth.rle" // Loaded when Life starts
+	lifeRule         = NewConwayRule()
+	lifeTitle        string // The window title without the rule
+	lifePrefs        *LifePrefs
+	lifeLaunch       *LaunchOptions // The command line options. nil if not started by the launcher
+	roundCheck       = widget.NewCheck("Round", nil)
 
 	FC_EMPTY  = color.RGBA{255, 0, 0, 255}   // Cell selector over an empty cell
 	FC_ADDED  = color.RGBA{0, 255, 0, 255}   // Cell just added
 	FC_FULL   = color.RGBA{0, 0, 255, 255}   // Cell selector over a cell
 	FC_SELECT = color.RGBA{255, 255, 0, 255} // Cell colour inside selection rectangle
 	FC_CELL   = color.RGBA{0, 255, 255, 255} // Normal, running cell colour
+	FC_PASTE  = color.RGBA{160, 80, 0, 160}  // Cells that are being pasted. Alpha premultiplied
 
 	COLOURS = []color.Color{FC_CELL, FC_SELECT, FC_FULL, FC_EMPTY} // Cell colour indexed by first two bits og the cell mode value
 )
 
 func POCLifeMouseEvent(me *MoverWidgetMouseEvent) {
+	if POCLifeMinimapEvent(me) || POCLifePanZoom(me) || (!lifeGenStopped && me.Event != MM_ME_DTAP) {
+		return // While running only pan, zoom and double tap are used
+	}
 	cellX1, cellY1 := lifeScreenToCell(float32(me.X1), float32(me.Y1))
 	// cellX2, cellY2 := lifeScreenToCell(float32(me.X2), float32(me.Y2))
+	if pasteGen != nil && me.Event == MM_ME_TAP {
+		if me.Button == int(desktop.MouseButtonPrimary) {
+			POCLifeEditCells("Paste", func() ([]int64, []int64) {
+				return lifeGen.AddNewCells(cellX1, cellY1, 0, pasteGen.ListCellsWithMode(0)), nil
+			})
+		}
+		POCLifePasteEnd()
+		return
+	}
+	if lifeTool != TOOL_SELECT && (me.Button == int(desktop.MouseButtonPrimary) || lifeToolDrawing) && POCLifeToolEvent(me) {
+		return
+	}
 	switch me.Event {
 	case MM_ME_TAP:
 		c := lifeGen.GetCell(cellX1, cellY1)
 		if me.Button == int(desktop.MouseButtonPrimary) {
-			if c == 0 {
-				lifeGen.AddCell(cellX1, cellY1, 0)
-				targetDot.FillColor = FC_ADDED
-			} else {
+			POCLifeEditCells("Toggle cell", func() ([]int64, []int64) {
+				if c == 0 {
+					lifeGen.AddCell(cellX1, cellY1, 0)
+					targetDot.FillColor = FC_ADDED
+					return []int64{cellX1, cellY1}, nil
+				}
 				lifeGen.RemoveCell(cellX1, cellY1)
 				targetDot.FillColor = FC_EMPTY
-			}
+				return nil, []int64{cellX1, cellY1}
+			})
 		} else {
 			if len(selectedCellsXY) > 0 {
-				lifeGen.AddCellsAtOffset(cellX1, cellY1, 0, selectedCellsXY)
+				POCLifeEditCells("Stamp", func() ([]int64, []int64) {
+					return lifeGen.AddNewCells(cellX1, cellY1, 0, selectedCellsXY), nil
+				})
 			}
 		}
 		targetDot.Show()
@@ -109,10 +196,11 @@ func POCLifeMouseEvent(me *MoverWidgetMouseEvent) {
 			targetRect.Resize(*me.Size())
 			targetRect.Show()
 		} else {
+			cursorCellX, cursorCellY = cellX1, cellY1
 			posX, posY := lifeCellToScreen(cellX1, cellY1)
 			targetDot.Position1 = fyne.Position{X: posX, Y: posY}
-			targetDot.Position2 = fyne.Position{X: posX + float32(gridSize), Y: posY + float32(gridSize)}
-			targetDot.Resize(fyne.Size{Width: float32(gridSize), Height: float32(gridSize)})
+			targetDot.Position2 = fyne.Position{X: posX + lifeDotSize(), Y: posY + lifeDotSize()}
+			targetDot.Resize(fyne.Size{Width: lifeDotSize(), Height: lifeDotSize()})
 			c := lifeGen.GetCell(cellX1, cellY1)
 			if c == 0 {
 				targetDot.FillColor = FC_EMPTY
@@ -125,6 +213,249 @@ func POCLifeMouseEvent(me *MoverWidgetMouseEvent) {
 	}
 }
 
+/*
+Draw with the current tool. The pencil and eraser change cells as the mouse moves.
+The other tools show the outline of the shape while dragging and add it when the mouse is released.
+The whole drag is one change for undo. Returns true if the event was used.
+*/
+func POCLifeToolEvent(me *MoverWidgetMouseEvent) bool {
+	cellX1, cellY1 := lifeScreenToCell(float32(me.X1), float32(me.Y1))
+	cellX2, cellY2 := lifeScreenToCell(float32(me.X2), float32(me.Y2))
+	switch me.Event {
+	case MM_ME_DOWN:
+		POCLifeStop()
+		lifeToolDrawing = true
+		lifeToolLastX, lifeToolLastY = cellX1, cellY1
+		if lifeTool.Paints() {
+			POCLifeToolPaint(LineCells(cellX1, cellY1, cellX1, cellY1))
+		}
+	case MM_ME_MOVE:
+		if !me.Dragging || !lifeToolDrawing {
+			return false
+		}
+		if lifeTool.Paints() {
+			POCLifeToolPaint(LineCells(lifeToolLastX, lifeToolLastY, cellX2, cellY2))
+			lifeToolLastX, lifeToolLastY = cellX2, cellY2
+			return true
+		}
+		tg := NewLifeGen(nil, 0)
+		tg.SetCells(lifeTool.PreviewCells(cellX1, cellY1, cellX2, cellY2))
+		toolGen = tg
+		pasteRenderer.Raster().Show()
+	case MM_ME_DRAG, MM_ME_TAP, MM_ME_UP:
+		if !lifeToolDrawing {
+			return false
+		}
+		if me.Event == MM_ME_TAP {
+			cellX2, cellY2 = cellX1, cellY1
+		}
+		if !lifeTool.Paints() {
+			density, _ := ParseToolDensity(toolDensity.Selected)
+			lifeToolAdded = append(lifeToolAdded, lifeGen.AddNewCells(0, 0, 0, lifeTool.Cells(cellX1, cellY1, cellX2, cellY2, density, lifeToolRand))...)
+		}
+		POCLifeToolPush()
+	}
+	return true
+}
+
+// Add (pencil) or remove (eraser) cells. They are recorded for undo
+func POCLifeToolPaint(cells []int64) {
+	if lifeTool == TOOL_ERASER {
+		lifeToolRemoved = append(lifeToolRemoved, lifeGen.RemoveCells(cells)...)
+	} else {
+		lifeToolAdded = append(lifeToolAdded, lifeGen.AddNewCells(0, 0, 0, cells)...)
+	}
+}
+
+/*
+Call to end a drag with a tool. The cells added and removed are one change for undo
+*/
+func POCLifeToolPush() {
+	if lifeToolDrawing {
+		lifeUndo.Push(NewLifeCellsChange(lifeTool.String(), lifeToolAdded, lifeToolRemoved))
+	}
+	lifeToolDrawing = false
+	lifeToolAdded = nil
+	lifeToolRemoved = nil
+	if toolGen != nil {
+		toolGen = nil
+		pasteRenderer.Raster().Hide()
+	}
+}
+
+/*
+Call to change the tool used by the primary mouse button. The density is only shown for random fill
+*/
+func POCLifeSetTool(t LifeTool) {
+	POCLifeToolPush()
+	lifeTool = t
+	if t == TOOL_RANDOM {
+		toolDensity.Show()
+	} else {
+		toolDensity.Hide()
+	}
+	targetRect.Hide()
+}
+
+/*
+Call to show or hide the population graph under the Life view
+*/
+func POCLifeGraphShow(show bool) {
+	if show {
+		lifeGraph.Show()
+		graphSeries.Show()
+	} else {
+		lifeGraph.Hide()
+		graphSeries.Hide()
+	}
+}
+
+/*
+Add the numbers for the current generation to the graph. The area is only found if it is shown as it needs all the cells to be scanned
+*/
+func POCLifeGraphSample() {
+	births, deaths := lifeGen.GetBirthsAndDeaths()
+	sample := PopulationSample{Gen: lifeGen.GetGenerationCount(), Population: lifeGen.GetCellCount(), Births: births, Deaths: deaths}
+	if lifeGraph.Visible() && lifeGraph.Showing(GRAPH_AREA) && lifeGen.GetRootCell() != nil {
+		x1, y1, x2, y2 := lifeGen.GetBounds()
+		sample.Area = (x2 - x1 + 1) * (y2 - y1 + 1)
+	}
+	if lifeGraph.Add(sample) && lifeGraph.Visible() {
+		lifeGraph.Refresh()
+	}
+}
+
+/*
+Call to put the selected cells (or all cells if none are selected) on the clipboard as RLE.
+If cut is true the selected cells are removed. Nothing is cut if there is no selection.
+*/
+func POCLifeCopy(cut bool) {
+	POCLifeStop()
+	coords := lifeGen.ListCellsWithMode(SELECT_MODE_MASK)
+	if len(coords) == 0 {
+		if cut {
+			return
+		}
+		coords = lifeGen.ListCellsWithMode(0)
+	}
+	if len(coords) == 0 {
+		return
+	}
+	lifeWindow.Clipboard().SetContent(ClipboardEncode(coords, lifeGen.GetRule().String()))
+	if cut {
+		POCLifeEditCells("Cut", func() ([]int64, []int64) {
+			return nil, lifeGen.RemoveCellsWithMode(SELECT_MODE_MASK)
+		})
+		selectedCellsXY = nil
+		targetRect.Hide()
+	}
+}
+
+/*
+Call to paste RLE (or Life 1.06 or Plaintext) from the clipboard.
+The cells follow the mouse until a click places them. A right click cancels.
+*/
+func POCLifePaste() {
+	rle, err := ClipboardDecode(lifeWindow.Clipboard().Content())
+	if err != nil {
+		errorContainer.SetErrorString(err.Error())
+		return
+	}
+	POCLifeStop()
+	pasteGen = NewLifeGen(nil, 0)
+	pasteGen.AddCellsAtOffset(0, 0, 0, rle.coords)
+	pasteRenderer.Raster().Show()
+}
+
+func POCLifePasteEnd() {
+	pasteGen = nil
+	pasteRenderer.Raster().Hide()
+}
+
+/*
+Clicking or dragging on the minimap centres the view on the cell under the mouse.
+Returns true if the event was used.
+*/
+func POCLifeMinimapEvent(me *MoverWidgetMouseEvent) bool {
+	if !lifeMinimap.Raster().Visible() {
+		return false
+	}
+	pos := lifeMinimap.Raster().Position()
+	inside := func(x, y int64) bool {
+		return float32(x) >= pos.X && float32(y) >= pos.Y && float32(x) < pos.X+MINIMAP_WIDTH && float32(y) < pos.Y+MINIMAP_HEIGHT
+	}
+	switch {
+	case me.Event == MM_ME_DOWN && inside(me.X1, me.Y1):
+		lifeMinimapDrag = true
+		POCLifeMinimapCentre(float32(me.X1)-pos.X, float32(me.Y1)-pos.Y)
+	case !lifeMinimapDrag:
+		return me.Event == MM_ME_MOVE && inside(me.X1, me.Y1) // Dont move the cursor under the minimap
+	case me.Event == MM_ME_MOVE && me.Dragging:
+		POCLifeMinimapCentre(float32(me.X2)-pos.X, float32(me.Y2)-pos.Y)
+	case me.Event == MM_ME_DRAG || me.Event == MM_ME_TAP || me.Event == MM_ME_UP:
+		lifeMinimapDrag = false
+	}
+	return true
+}
+
+/*
+Centre the view on the cell at x,y on the minimap
+*/
+func POCLifeMinimapCentre(x, y float32) {
+	cellX, cellY := lifeMinimap.CellAt(x, y)
+	size := moverWidget.Size()
+	xOffset = float64(size.Width)/2/gridSize - cellX
+	yOffset = float64(size.Height)/2/gridSize - cellY
+}
+
+/*
+Call to show or hide the minimap
+*/
+func POCLifeMinimapShow(show bool) {
+	if show {
+		lifeMinimap.Raster().Show()
+	} else {
+		lifeMinimap.Raster().Hide()
+		lifeMinimapDrag = false
+	}
+}
+
+/*
+Handle the mouse wheel (zoom around the cursor) and dragging with the middle button or space held down (pan).
+Returns true if the event was used.
+*/
+func POCLifePanZoom(me *MoverWidgetMouseEvent) bool {
+	switch {
+	case me.Event == MM_ME_SCRL:
+		POCLifeZoomAt(float32(me.X1), float32(me.Y1), math.Pow(LIFE_ZOOM_STEP, float64(me.ScrollY)/10))
+		return true
+	case me.Event == MM_ME_DOWN && (me.Button == int(desktop.MouseButtonTertiary) || lifeSpaceDown):
+		lifePanning = true
+		lifePanX, lifePanY = xOffset, yOffset
+		return true
+	case !lifePanning:
+		return false
+	case me.Event == MM_ME_MOVE && me.Dragging:
+		xOffset = lifePanX + float64(me.X2-me.X1)/gridSize
+		yOffset = lifePanY + float64(me.Y2-me.Y1)/gridSize
+	case me.Event == MM_ME_DRAG || me.Event == MM_ME_TAP || me.Event == MM_ME_UP:
+		lifePanning = false
+	}
+	return true
+}
+
+/*
+Change the cell size by factor keeping the cell at x,y (in the view) in the same place
+*/
+func POCLifeZoomAt(x, y float32, factor float64) {
+	cellX := float64(x)/gridSize - xOffset
+	cellY := float64(y)/gridSize - yOffset
+	gridSize = math.Max(LIFE_GRID_MIN, math.Min(LIFE_GRID_MAX, gridSize*factor))
+	xOffset = float64(x)/gridSize - cellX
+	yOffset = float64(y)/gridSize - cellY
+	targetDot.Resize(fyne.Size{Width: lifeDotSize(), Height: lifeDotSize()})
+}
+
 func POCLifeKeyPress(key string) {
 	switch key {
 	case "F1":
@@ -140,13 +471,35 @@ func POCLifeKeyPress(key string) {
 		}
 		return
 	case "Up":
-		yOffset = yOffset + 50
+		if !POCLifeNudge(0, -1) {
+			yOffset = yOffset + 50*math.Max(1, 1/gridSize)
+		}
 	case "Down":
-		yOffset = yOffset - 50
+		if !POCLifeNudge(0, 1) {
+			yOffset = yOffset - 50*math.Max(1, 1/gridSize)
+		}
 	case "Left":
-		xOffset = xOffset + 50
+		if !POCLifeNudge(-1, 0) {
+			xOffset = xOffset + 50*math.Max(1, 1/gridSize)
+		}
 	case "Right":
-		xOffset = xOffset - 50
+		if !POCLifeNudge(1, 0) {
+			xOffset = xOffset - 50*math.Max(1, 1/gridSize)
+		}
+	case "R":
+		POCLifeTransform(SYM_ROT_90)
+	case "L":
+		POCLifeTransform(SYM_ROT_270)
+	case "H":
+		POCLifeTransform(SYM_FLIP_X)
+	case "V":
+		POCLifeTransform(SYM_FLIP_Y)
+	case "M":
+		minimapCheck.SetChecked(!minimapCheck.Checked)
+	case "G":
+		graphCheck.SetChecked(!graphCheck.Checked)
+	case "1", "2", "3", "4", "5", "6", "7":
+		toolSelect.SetSelectedIndex(int(key[0] - '1'))
 	case "=", "+":
 		POCLifeSetGridSize(true)
 	case "-", "_":
@@ -159,13 +512,56 @@ func POCLifeKeyPress(key string) {
 		POCLifeHome()
 	}
 }
+
+/*
+Call to rotate or flip the cells being pasted or the selected cells.
+R and L rotate clockwise and anticlockwise. H and V flip left to right and top to bottom.
+*/
+func POCLifeTransform(s LifeSymmetry) {
+	if pg := pasteGen; pg != nil {
+		coords, _, _ := TransformCoords(pg.ListCellsWithMode(0), s)
+		pg = NewLifeGen(nil, 0)
+		pg.AddCellsAtOffset(0, 0, 0, coords)
+		pasteGen = pg
+		return
+	}
+	if !lifeGenStopped || lifeGen.CountCellsWithMode(SELECT_MODE_MASK) == 0 {
+		return
+	}
+	POCLifeEdit(s.String(), func() {
+		lifeGen.TransformCellsWithMode(SELECT_MODE_MASK, s)
+	})
+	POCLifeSelectionChanged()
+}
+
+/*
+Call to move the selected cells by one cell. The arrow keys move the view if there is no selection.
+Returns true if cells were moved.
+*/
+func POCLifeNudge(dx, dy int64) bool {
+	if !lifeGenStopped || lifeGen.CountCellsWithMode(SELECT_MODE_MASK) == 0 {
+		return false
+	}
+	POCLifeEdit("Move", func() {
+		lifeGen.MoveCellsWithMode(SELECT_MODE_MASK, dx, dy)
+	})
+	POCLifeSelectionChanged()
+	return true
+}
+
+// The selected cells have been moved so the selection rectangle is out of date
+func POCLifeSelectionChanged() {
+	selectedCellsXY, _, _ = POCNormaliseCoords(lifeGen.ListCellsWithMode(SELECT_MODE_MASK))
+	targetRect.Hide()
+}
+
 func POCLifeHome() {
 	runsRemaining := POCLifeStop()
-	midX := (int64(lifeWindow.Canvas().Size().Width) / gridSize)
-	midY := (int64(lifeWindow.Canvas().Size().Height) / gridSize)
+	midX := math.Floor(float64(lifeWindow.Canvas().Size().Width) / gridSize)
+	midY := math.Floor(float64(lifeWindow.Canvas().Size().Height) / gridSize)
 	x1, y1, x2, y2 := lifeGen.GetBounds()
-	xOffset = ((midX - (x2 - x1)) / 2) - x1
-	yOffset = ((midY - (y2 - y1)) / 2) - y1
+	xOffset = math.Floor((midX-float64(x2-x1))/2) - float64(x1)
+	yOffset = math.Floor((midY-float64(y2-y1))/2) - float64(y1)
 	if runsRemaining > 0 {
 		POCLifeRunFor(runsRemaining)
 	}
@@ -191,15 +587,14 @@ func POCLifeSetFaster() {
 	lifeController.SetAnimationDelay(currentDelay)
 }
 
+// Zoom in or out around the centre of the view
 func POCLifeSetGridSize(inc bool) {
-	if inc {
-		gridSize++
-	} else {
-		if gridSize > 1 {
-			gridSize--
-		}
+	factor := LIFE_ZOOM_STEP
+	if !inc {
+		factor = 1 / LIFE_ZOOM_STEP
 	}
-	targetDot.Resize(fyne.Size{Width: float32(gridSize), Height: float32(gridSize)})
+	size := moverWidget.Size()
+	POCLifeZoomAt(size.Width/2, size.Height/2, factor)
 }
 
 func POCLifeStop() int {
@@ -214,7 +609,12 @@ func POCLifeStop() int {
 		}
 	}
 	lifeGenStopped = true
-	moverWidget.SetOnMouseEvent(POCLifeMouseEvent, MM_ME_MOVE|MM_ME_DOWN|MM_ME_UP|MM_ME_TAP|MM_ME_DTAP)
+	if before := lifeRunBefore; before != nil {
+		lifeRunBefore = nil
+		n := lifeGen.GetGenerationCount() - lifeRunGen
+		lifeUndo.Push(NewLifeEdit(fmt.Sprintf("Run %d generation(s)", n), before, lifeGen.ListCellsWithMode(0), nil, nil, lifeRunGen, lifeGen.GetGenerationCount()))
+	}
+	moverWidget.SetOnMouseEvent(POCLifeMouseEvent, MM_ME_MOVE|MM_ME_DOWN|MM_ME_UP|MM_ME_TAP|MM_ME_DTAP|MM_ME_SCRL)
 
 	lifeController.SetAnimationDelay(200)
 	targetDot.Show()
@@ -226,11 +626,17 @@ func POCLifeStop() int {
 }
 
 func POCLifeRunFor(n int) {
+	POCLifeToolPush()
+	if lifeRunBefore == nil {
+		lifeRunGen = lifeGen.GetGenerationCount()
+		lifeRunBefore = lifeGen.ListCellsWithMode(0)
+	}
 	lifeGen.SetRunFor(n, func(lg *LifeGen) {
 		POCLifeStop()
 	})
 	lifeGenStopped = false
-	moverWidget.SetOnMouseEvent(POCLifeMouseEvent, MM_ME_DTAP)
+	moverWidget.SetOnMouseEvent(POCLifeMouseEvent, MM_ME_MOVE|MM_ME_DOWN|MM_ME_UP|MM_ME_DTAP|MM_ME_SCRL)
+	POCLifePasteEnd()
 	lifeController.SetAnimationDelay(currentDelay)
 	targetDot.Hide()
 	targetRect.Hide()
@@ -242,6 +648,49 @@ func POCLifeRunFor(n int) {
 	saveButton.Hide()
 }
 
+/*
+Call to stop and make a change to the universe that can be undone.
+The cells, the rule and the generation count before and after f are compared so f can make any change.
+Used for changes to many cells such as clear, load and transform. See POCLifeEditCells for small changes.
+*/
+func POCLifeEdit(name string, f func()) {
+	POCLifeStop()
+	before := lifeGen.ListCellsWithMode(0)
+	rule := lifeGen.GetRule()
+	gen := lifeGen.GetGenerationCount()
+	f()
+	lifeUndo.Push(NewLifeEdit(name, before, lifeGen.ListCellsWithMode(0), rule, lifeGen.GetRule(), gen, lifeGen.GetGenerationCount()))
+	POCLifeRuleChanged()
+}
+
+/*
+Call to stop and make a change to the cells that can be undone.
+f makes the change and returns the cells it added and removed so the other cells are not listed.
+Used for toggle, paste and delete so a small change in a large universe is cheap.
+*/
+func POCLifeEditCells(name string, f func() ([]int64, []int64)) {
+	POCLifeStop()
+	added, removed := f()
+	lifeUndo.Push(NewLifeCellsChange(name, added, removed))
+}
+
+/*
+Call to undo (Ctrl-Z) or redo (Ctrl-Y) the last change. A run of generations is undone in one step.
+*/
+func POCLifeUndo(redo bool) {
+	POCLifeStop()
+	var cmd LifeCommand
+	if redo {
+		cmd = lifeUndo.Redo(lifeGen)
+	} else {
+		cmd = lifeUndo.Undo(lifeGen)
+	}
+	if cmd != nil {
+		POCLifeSelectionChanged()
+		POCLifeRuleChanged()
+	}
+}
+
 /*
 Call if Saving cells in selectedCellsXY
 */
@@ -249,30 +698,266 @@ func POCLifeFileSave() {
 	if len(selectedCellsXY) > 0 {
 		POCLifeStop()
 		fbWidget.SetOnSelectedEvent(nil)
+		fbWidget.SetSavePrompt("Save Selected Cells to a RLE File")
+		exportForm.Hide()
+		saveRleForm.Show()
 		fbWidget.SetOnSaveEvent(func(path string, save bool, err error) error {
 			if save {
-				rle := NewRLESave(path, selectedCellsXY, ownerEntry.Text, descriptionEntry.Text)
+				coords := selectedCellsXY
+				if saveCanonical.Checked {
+					coords, _ = CanonicalCoords(coords)
+				}
+				rle := NewRLESave(path, coords, ownerEntry.Text, descriptionEntry.Text)
+				rle.rule = lifeGen.GetRule().String()
 				err := rle.Save()
 				if err != nil {
 					errorContainer.SetErrorString(err.Error())
 					return nil
 				}
+				currentSaveDir = filepath.Dir(path)
 			}
 			fbWidget.Hide()
 			saveContainer.Hide()
 			return nil
 		})
-		fbWidget.SetPath(currentWd)
+		fbWidget.SetPath(currentSaveDir)
 		fbWidget.Show()
 		saveContainer.Show()
 	}
 }
 
+/*
+Call to export the selected cells (or all cells if none are selected) to a png or svg file
+*/
+func POCLifeFileExport() {
+	POCLifeStop()
+	fbWidget.SetOnSelectedEvent(nil)
+	if len(selectedCellsXY) > 0 {
+		fbWidget.SetSavePrompt("Export Selected Cells to an Image File (.png, .svg or .gif)")
+	} else {
+		fbWidget.SetSavePrompt("Export All Cells to an Image File (.png, .svg or .gif)")
+	}
+	saveRleForm.Hide()
+	exportForm.Show()
+	fbWidget.SetOnSaveEvent(func(path string, save bool, err error) error {
+		if save {
+			opts := NewImageExportOptions()
+			size, err := strconv.Atoi(strings.TrimSpace(exportSizeEntry.Text))
+			if err != nil || size < 1 {
+				errorContainer.SetErrorString(fmt.Sprintf("Cell size '%s' must be a number greater than 0", exportSizeEntry.Text))
+				return nil
+			}
+			opts.CellSize = size
+			opts.Grid = exportGrid.Checked
+			opts.Scheme = FindExportScheme(exportScheme.Selected)
+			if opts.Scheme == nil {
+				opts.Scheme = EXPORT_SCHEMES[0]
+			}
+			if strings.HasSuffix(strings.ToLower(path), ".gif") {
+				err = POCLifeExportGif(path, opts)
+			} else {
+				if len(selectedCellsXY) > 0 {
+					err = ExportCellsImage(path, selectedCellsXY, nil, opts)
+				} else {
+					err = ExportLifeGenImage(path, lifeGen, opts)
+				}
+			}
+			if err != nil {
+				errorContainer.SetErrorString(err.Error())
+				return nil
+			}
+			currentSaveDir = filepath.Dir(path)
+		}
+		fbWidget.Hide()
+		saveContainer.Hide()
+		return nil
+	})
+	exportSizeEntry.SetText(fmt.Sprintf("%d", int(math.Max(1, math.Round(gridSize)))))
+	fbWidget.SetPath(currentSaveDir)
+	fbWidget.Show()
+	saveContainer.Show()
+}
+
+/*
+Run the selected cells (or all cells if none are selected) and save as an animated gif
+*/
+func POCLifeExportGif(path string, opts *ImageExportOptions) error {
+	gifOpts := NewGifExportOptions()
+	gifOpts.ImageExportOptions = *opts
+	gens, err := strconv.Atoi(strings.TrimSpace(exportGensEntry.Text))
+	if err != nil || gens < 1 {
+		return fmt.Errorf("generations '%s' must be a number greater than 0", exportGensEntry.Text)
+	}
+	gifOpts.Generations = gens
+	gifOpts.Delay = int(currentDelay / 10)
+	lg := lifeGen
+	if len(selectedCellsXY) > 0 {
+		lg = NewLifeGen(nil, RUN_FOR_EVER)
+		lg.SetRule(lifeGen.GetRule())
+		lg.AddCellsAtOffset(0, 0, 0, selectedCellsXY)
+	}
+	return ExportLifeGenGIF(path, lg, gifOpts)
+}
+
+/*
+Call to show or hide the apgcode entry.
+*/
+func POCLifeApgShow() {
+	if apgContainer.Visible() {
+		apgContainer.Hide()
+	} else {
+		POCLifeStop()
+		apgContainer.Show()
+		lifeWindow.Canvas().Focus(apgEntry)
+	}
+}
+
+/*
+Call to stamp the object defined by the apgcode at the cursor.
+*/
+func POCLifeApgPaste() {
+	coords, err := ApgCodeDecode(apgEntry.Text)
+	if err != nil {
+		errorContainer.SetErrorString(err.Error())
+		return
+	}
+	POCLifeEditCells("Paste apgcode", func() ([]int64, []int64) {
+		return lifeGen.AddNewCells(cursorCellX, cursorCellY, 0, coords), nil
+	})
+	apgContainer.Hide()
+}
+
+/*
+Call to show or hide the rule entry. It starts with the current rule
+*/
+func POCLifeRuleShow() {
+	if ruleContainer.Visible() {
+		ruleContainer.Hide()
+	} else {
+		POCLifeStop()
+		rule := lifeGen.GetRule()
+		ruleEntry.SetText(rule.String())
+		if rule.Name() == "" {
+			ruleNamed.ClearSelected()
+		} else {
+			ruleNamed.SetSelected(rule.Name())
+		}
+		ruleContainer.Show()
+		lifeWindow.Canvas().Focus(ruleEntry)
+	}
+}
+
+/*
+Call to apply the rule in the rule entry. The cells are not changed and the change can be undone
+*/
+func POCLifeRuleApply() {
+	rule, err := ParseLifeRule(ruleEntry.Text)
+	if err != nil {
+		errorContainer.SetErrorString(err.Error())
+		return
+	}
+	POCLifeEdit(fmt.Sprintf("Rule %s", rule.String()), func() {
+		lifeGen.SetRule(rule)
+	})
+	ruleContainer.Hide()
+}
+
+/*
+Call to set the window title. The current rule is shown after it
+*/
+func POCLifeSetTitle(title string) {
+	lifeTitle = title
+	POCLifeRuleChanged()
+}
+
+/*
+Call when the rule may have changed to update the rule button and the window title
+*/
+func POCLifeRuleChanged() {
+	rule := lifeGen.GetRule()
+	ruleButton.SetText(fmt.Sprintf("Rule: %s", rule.String()))
+	lifeWindow.SetTitle(strings.TrimSpace(fmt.Sprintf("%s Rule:%s", lifeTitle, rule.Title())))
+}
+
+/*
+Call to apply the preferences to the Life view. Used at startup and by Defaults
+*/
+func POCLifeApplyPrefs(p *LifePrefs) {
+	currentWd = p.Wd
+	if currentWd == "" {
+		currentWd, _ = os.Getwd()
+	}
+	currentSaveDir = p.SaveDir
+	if currentSaveDir == "" {
+		currentSaveDir = currentWd
+	}
+	gridSize, xOffset, yOffset = p.GridSize, p.XOffset, p.YOffset
+	currentDelay = p.Delay
+	lifeController.SetAnimationDelay(currentDelay)
+	if currentDelay <= 10 {
+		fasterButton.Disable()
+	} else {
+		fasterButton.Enable()
+	}
+	if currentDelay >= 400 {
+		slowerButton.Disable()
+	} else {
+		slowerButton.Enable()
+	}
+	ownerEntry.SetText(p.Owner)
+	if p.ExportScheme == "" {
+		exportScheme.SetSelected(EXPORT_SCHEMES[0].Name)
+	} else {
+		exportScheme.SetSelected(FindExportScheme(p.ExportScheme).Name)
+	}
+	roundCheck.SetChecked(p.Round)
+	if p.Width > 0 && p.Height > 0 {
+		lifeWindow.Resize(fyne.NewSize(p.Width, p.Height))
+	}
+}
+
+/*
+Call to save the current settings to the preferences file
+*/
+func POCLifeSavePrefs() error {
+	p := lifePrefs
+	p.Wd, p.SaveDir = currentWd, currentSaveDir
+	p.GridSize, p.XOffset, p.YOffset = gridSize, xOffset, yOffset
+	p.Delay = currentDelay
+	p.Owner = ownerEntry.Text
+	p.ExportScheme = exportScheme.Selected
+	p.Round = roundCheck.Checked
+	size := lifeWindow.Canvas().Size()
+	p.Width, p.Height = size.Width, size.Height
+	return p.Save()
+}
+
+/*
+Call to set the preferences back to their defaults. They are applied and saved straight away
+*/
+func POCLifeResetPrefs() {
+	lifePrefs.Reset()
+	POCLifeApplyPrefs(lifePrefs)
+	if err := lifePrefs.Save(); err != nil {
+		errorContainer.SetErrorString(err.Error())
+	}
+}
+
+/*
+Called by the launcher after the window is closed
+*/
+func POCLifeClose() error {
+	if lifePrefs == nil {
+		return nil
+	}
+	return POCLifeSavePrefs()
+}
+
 /*
 Call if loading RLE at Offset and clearing the existing cells first
 */
 func POCLifeFileLoad() {
-	POCLifeFile(xOffset, yOffset, true)
+	POCLifeFile(int64(xOffset), int64(yOffset), true)
 }
 
 /*
@@ -286,35 +971,224 @@ func POCLifeFile(cellPosX, cellPosY int64, clearCells bool) {
 		POCLifeStop()
 		fbWidget.SetPath(currentWd)
 		fbWidget.SetOnSelectedEvent(func(fil, path string) error {
-			POCLifeStop()
-			rleFile, rleError = NewRleFile(fil)
-			if rleError != nil {
-				errorContainer.SetErrorString(rleError.Error())
-				return rleError
+			if IsScriptFile(fil) {
+				return POCLifeRunScript(fil)
 			}
-			currentWd = path
-			if clearCells {
-				lifeGen.Reset()
+			err := POCLifeLoadFile(fil, cellPosX, cellPosY, clearCells)
+			if err == nil {
+				currentWd = path
 			}
-			ofsx, ofsy := rleFile.Center()
-			lifeGen.AddCellsAtOffset(cellPosX-ofsx, cellPosY-ofsy, 0, rleFile.coords)
-			POCLifeRunFor(RUN_FOR_EVER)
-			lifeWindow.SetTitle(fil)
-			return nil
+			return err
 		})
 		fbWidget.Show()
+		POCLifeIndexUpdate(func(pi *PatternIndex, err error) {
+			if pi != nil && fbWidget.Visible() {
+				fbWidget.Reload() // Show the comments from the updated index
+			}
+		})
+	}
+}
+
+/*
+Call to stop, load a pattern file and place its centre at a position. Optionally clearing the existing cells first
+*/
+func POCLifeLoadFile(fil string, cellPosX, cellPosY int64, clearCells bool) error {
+	POCLifeStop()
+	rleFile, rleError = LoadPattern(fil)
+	if rleError != nil {
+		errorContainer.SetErrorString(rleError.Error())
+		return rleError
+	}
+	POCLifeEdit(fmt.Sprintf("Load %s", path.Base(fil)), func() {
+		if clearCells {
+			lifeGen.Reset()
+		}
+		ofsx, ofsy := rleFile.Center()
+		lifeGen.AddCellsAtOffset(cellPosX-ofsx, cellPosY-ofsy, 0, rleFile.coords)
+	})
+	POCLifeRunFor(RUN_FOR_EVER)
+	POCLifeSetTitle(fil)
+	lifePrefs.LastFile = fil
+	return nil
+}
+
+/*
+Call to stop and run a script file against a copy of the current cells.
+The script runs in the background. When it has finished the frame loop replaces the cells with the result so it can be undone.
+Errors include the line number of the script and the cells are not changed
+*/
+func POCLifeRunScript(fil string) error {
+	POCLifeStop()
+	run := lifeGen.Copy()
+	go func() {
+		err := NewLifeScript(fil, run, nil).RunFile()
+		POCLifeOnFrame(func() {
+			if err != nil {
+				errorContainer.SetErrorString(err.Error())
+				return
+			}
+			POCLifeEdit(fmt.Sprintf("Script %s", path.Base(fil)), func() {
+				lifeGen.Replace(run, run.GetGenerationCount())
+			})
+			POCLifeSetTitle(fmt.Sprintf("Script:%s", fil))
+		})
+	}()
+	return nil
+}
+
+/*
+Call from a background goroutine to change lifeGen or the widgets.
+f is run by the frame loop before the next generation so it does not race with NextGen or drawing
+*/
+func POCLifeOnFrame(f func()) {
+	lifeFrameMu.Lock()
+	defer lifeFrameMu.Unlock()
+	lifeFrameTasks = append(lifeFrameTasks, f)
+}
+
+/*
+Called by the frame loop to run the functions given to POCLifeOnFrame
+*/
+func POCLifeRunFrameTasks() {
+	lifeFrameMu.Lock()
+	tasks := lifeFrameTasks
+	lifeFrameTasks = nil
+	lifeFrameMu.Unlock()
+	for _, f := range tasks {
+		f()
 	}
 }
 
+/*
+Call to update the pattern index for the current directory tree in the background.
+The index is kept in the user cache directory so next time it only reads files that have changed.
+done is called on the background goroutine with the new index. The index is nil if the update failed
+*/
+func POCLifeIndexUpdate(done func(*PatternIndex, error)) {
+	root := currentWd
+	go func() {
+		lifeIndexUpdate.Lock()
+		defer lifeIndexUpdate.Unlock()
+		cacheFile := PatternIndexCacheFile(root)
+		pi, err := LoadPatternIndex(root, cacheFile)
+		if err != nil {
+			pi = NewPatternIndex(root, cacheFile) // The cache is invalid so start again
+		}
+		if _, err := pi.Update(); err != nil {
+			done(nil, err)
+			return
+		}
+		lifeIndexMu.Lock()
+		lifeIndex = pi
+		lifeIndexMu.Unlock()
+		done(pi, pi.Save())
+	}()
+}
+
+/*
+Call to get the latest pattern index. nil until the first update has finished
+*/
+func POCLifeIndex() *PatternIndex {
+	lifeIndexMu.Lock()
+	defer lifeIndexMu.Unlock()
+	return lifeIndex
+}
+
+/*
+Called by the file browser for the text after a pattern file name. Files are not read here.
+If the file is not in the index only the name is shown until the index has been updated
+*/
+func POCLifeFileComment(fileName string) string {
+	pi := POCLifeIndex()
+	if pi == nil {
+		return ""
+	}
+	e := pi.Lookup(fileName)
+	if e == nil {
+		return ""
+	}
+	if e.Error != "" {
+		return e.Error
+	}
+	return e.Comment()
+}
+
+/*
+Call to show or hide the pattern search.
+*/
+func POCLifeFindShow() {
+	if findContainer.Visible() {
+		findContainer.Hide()
+	} else {
+		POCLifeStop()
+		findContainer.Show()
+		POCLifeFind()
+		lifeWindow.Canvas().Focus(findEntry)
+	}
+}
+
+/*
+Call to search the pattern index using the text in the find entry and list the results.
+*/
+func POCLifeFind() {
+	q, err := ParsePatternQuery(findEntry.Text)
+	if err != nil {
+		errorContainer.SetErrorString(err.Error())
+		return
+	}
+	findLabel.SetText("Indexing...")
+	POCLifeIndexUpdate(func(pi *PatternIndex, err error) {
+		if err != nil {
+			errorContainer.SetErrorString(err.Error())
+		}
+		if pi == nil {
+			findLabel.SetText("")
+			return
+		}
+		findIndex = pi
+		findResults = pi.Search(q)
+		findLabel.SetText(fmt.Sprintf("Found %d of %d", len(findResults), len(pi.Entries)))
+		findList.UnselectAll()
+		findList.Refresh()
+	})
+}
+
 /*
 -------------------------------------------------------------------- main
 */
 func MainPOCLife(mainWindow fyne.Window, width, height float64, moverController *MoverController) *fyne.Container {
 	lifeWindow = mainWindow
 	lifeController = moverController
-	lifeController.SetAnimationDelay(100)
-	currentWd, _ = os.Getwd()
+	lifeController.SetAnimationDelay(currentDelay)
+	prefsFile := ""
+	if lifeLaunch != nil {
+		prefsFile = lifeLaunch.Prefs
+	}
+	var prefsErr error
+	lifePrefs, prefsErr = LoadLifePrefs(prefsFile)
+	if prefsErr != nil {
+		lifePrefs = NewLifePrefs(prefsFile)
+	}
+	// Values given on the command line replace the preferences
+	if lifeLaunch.Given("delay") {
+		lifePrefs.Delay = currentDelay
+	}
+	if lifeLaunch.Given("pattern") {
+		lifePrefs.LastFile = lifeStartFile
+	} else if lifePrefs.LastFile != "" {
+		lifeStartFile = lifePrefs.LastFile
+	}
+	if lifeLaunch.Given("width") || lifeLaunch.Given("height") {
+		lifePrefs.Width, lifePrefs.Height = 0, 0
+	}
 	moverWidget = NewMoverWidget(width, height)
+	lifeRenderer = NewLifeRenderer()
+	lifeMinimap = NewLifeMinimap()
+	pasteRenderer = NewLifeRenderer()
+	for i := range pasteRenderer.Colours {
+		pasteRenderer.Colours[i] = FC_PASTE
+	}
+	pasteRenderer.Raster().Hide()
 	targetDot = canvas.NewCircle(color.RGBA{250, 0, 0, 255})
 	targetRect = &canvas.Rectangle{StrokeColor: color.RGBA{250, 0, 0, 255}, StrokeWidth: 1}
 	fbWidget = NewFileBrowserWidget(width, height)
@@ -327,13 +1201,11 @@ func MainPOCLife(mainWindow fyne.Window, width, height float64, moverController
 		if typ == FB_DIR {
 			return de.Name()
 		}
-		if strings.HasSuffix(strings.ToLower(name), ".rle") {
-			rle, e := NewRleFile(path.Join(rootPath, name))
-			if e != nil {
-				return fmt.Sprintf("%s | %s", name, e.Error())
-			} else {
-				return fmt.Sprintf("%s | %s", name, rle.comment)
-			}
+		if IsScriptFile(name) {
+			return fmt.Sprintf("%s | script", name)
+		}
+		if IsPatternFile(name) {
+			return fmt.Sprintf("%s | %s", name, POCLifeFileComment(path.Join(rootPath, name)))
 		}
 		return ""
 	})
@@ -353,11 +1225,13 @@ func MainPOCLife(mainWindow fyne.Window, width, height float64, moverController
 		POCLifeStop()
 	})
 	clearButton = widget.NewButton("Clear", func() {
-		lifeGen.Reset()
+		POCLifeEdit("Clear", lifeGen.Reset)
 	})
 	deleteButton = widget.NewButton("Delete", func() {
 		if len(selectedCellsXY) > 0 {
-			lifeGen.RemoveCellsWithMode(SELECT_MODE_MASK)
+			POCLifeEditCells("Delete", func() ([]int64, []int64) {
+				return nil, lifeGen.RemoveCellsWithMode(SELECT_MODE_MASK)
+			})
 		}
 	})
 	saveButton = widget.NewButton("Save", func() {
@@ -381,10 +1255,12 @@ func MainPOCLife(mainWindow fyne.Window, width, height float64, moverController
 	}))
 	topC.Add(lifeSeperator())
 	topC.Add(widget.NewButton("File", POCLifeFileLoad))
+	topC.Add(widget.NewButton("Find", POCLifeFindShow))
 	topC.Add(widget.NewButton("Restart", func() {
-		POCLifeStop()
-		lifeGen.Reset()
-		lifeGen.AddCellsAtOffset(xOffset, yOffset, 0, rleFile.coords)
+		POCLifeEdit("Restart", func() {
+			lifeGen.Reset()
+			lifeGen.AddCellsAtOffset(int64(xOffset), int64(yOffset), 0, rleFile.coords)
+		})
 	}))
 	topC.Add(lifeSeperator())
 	topC.Add(startButton)
@@ -417,25 +1293,104 @@ func MainPOCLife(mainWindow fyne.Window, width, height float64, moverController
 	}))
 	topC.Add(fasterButton)
 	topC.Add(slowerButton)
+	roundCheck.OnChanged = func(b bool) {
+		lifeRenderer.Round = b
+	}
+	topC.Add(roundCheck)
+	minimapCheck.OnChanged = POCLifeMinimapShow
+	minimapCheck.SetChecked(true)
+	topC.Add(minimapCheck)
+	topC.Add(lifeSeperator())
+	topC.Add(widget.NewLabel("Tool (1-7):"))
+	toolSelect.OnChanged = func(name string) {
+		t, err := ParseLifeTool(name)
+		if err == nil {
+			POCLifeSetTool(t)
+		}
+		mainWindow.Canvas().Unfocus() // So the keys go to the Life view
+	}
+	toolSelect.SetSelectedIndex(int(TOOL_SELECT))
+	topC.Add(toolSelect)
+	toolDensity.OnChanged = func(string) {
+		mainWindow.Canvas().Unfocus()
+	}
+	toolDensity.SetSelected("37%")
+	toolDensity.Hide()
+	topC.Add(toolDensity)
 	topC.Add(lifeSeperator())
 	topC.Add(deleteButton)
 	topC.Add(saveButton)
+	topC.Add(widget.NewButton("Image", POCLifeFileExport))
+	topC.Add(lifeSeperator())
+	topC.Add(widget.NewButton("ApgCode", POCLifeApgShow))
+	ruleButton = widget.NewButton("Rule:", POCLifeRuleShow)
+	topC.Add(ruleButton)
+	topC.Add(lifeSeperator())
+	topC.Add(widget.NewButton("Defaults", POCLifeResetPrefs))
 
-	botC.Add(timeText)
-	rleFile, rleError = NewRleFile("testdata/Infinite_growth.rle")
-	if rleError != nil {
-		panic(rleError)
+	lifeGraph = NewPopulationGraph()
+	graphSeries = container.NewHBox()
+	for i, name := range GraphSeriesNames {
+		series := i
+		check := widget.NewCheck(name, func(b bool) {
+			lifeGraph.SetShow(series, b)
+		})
+		check.SetChecked(lifeGraph.Showing(series))
+		graphSeries.Add(check)
+	}
+	graphCheck.OnChanged = POCLifeGraphShow
+	graphCheck.SetChecked(false)
+	POCLifeGraphShow(false)
+	botC.Add(container.NewVBox(lifeGraph, container.NewHBox(graphCheck, graphSeries, timeText)))
+	POCLifeApplyPrefs(lifePrefs)
+	if prefsErr != nil {
+		errorContainer.SetErrorString(prefsErr.Error())
 	}
 	lifeGen = NewLifeGen(nil, 0)
-	lifeGen.AddCellsAtOffset(10, 10, 0, rleFile.coords)
+	lifeGen.SetRule(lifeRule)
+	rleFile, rleError = LoadPattern(lifeStartFile)
+	if rleError != nil {
+		errorContainer.SetErrorString(rleError.Error())
+	} else {
+		lifeGen.AddCellsAtOffset(10, 10, 0, rleFile.coords)
+		lifeTitle = fmt.Sprintf("File:%s", rleFile.fileName)
+	}
+	POCLifeRuleChanged()
 	POCLifeRunFor(RUN_FOR_EVER)
-	mainWindow.SetTitle(fmt.Sprintf("File:%s", rleFile.fileName))
 
+	if dc, ok := mainWindow.Canvas().(desktop.Canvas); ok {
+		dc.SetOnKeyDown(func(key *fyne.KeyEvent) {
+			if key.Name == fyne.KeySpace {
+				lifeSpaceDown = true
+			}
+		})
+		dc.SetOnKeyUp(func(key *fyne.KeyEvent) {
+			if key.Name == fyne.KeySpace {
+				lifeSpaceDown = false
+			}
+		})
+	}
+	mainWindow.Canvas().AddShortcut(&fyne.ShortcutCopy{}, func(fyne.Shortcut) {
+		POCLifeCopy(false)
+	})
+	mainWindow.Canvas().AddShortcut(&fyne.ShortcutCut{}, func(fyne.Shortcut) {
+		POCLifeCopy(true)
+	})
+	mainWindow.Canvas().AddShortcut(&fyne.ShortcutPaste{}, func(fyne.Shortcut) {
+		POCLifePaste()
+	})
+	mainWindow.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: desktop.ControlModifier}, func(fyne.Shortcut) {
+		POCLifeUndo(false)
+	})
+	mainWindow.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyY, Modifier: desktop.ControlModifier}, func(fyne.Shortcut) {
+		POCLifeUndo(true)
+	})
 	lifeController.SetOnKeyPress(func(key *fyne.KeyEvent) {
 		POCLifeKeyPress(string(key.Name))
 	})
 
 	lifeController.AddBeforeUpdate(func(f float64) bool {
+		POCLifeRunFrameTasks()
 		if lifeGenStopped {
 			if len(selectedCellsXY) > 0 {
 				if !saveButton.Visible() {
@@ -458,70 +1413,113 @@ func MainPOCLife(mainWindow fyne.Window, width, height float64, moverController
 
 		}
 		lifeGen.NextGen()
-		POCLifeResetDot()
-		cell := lifeGen.GetRootCell()
-		for cell != nil {
-			POCLifeGetDot(cell.x, cell.y, cell.mode, moverWidget)
-			cell = cell.next
+		POCLifeGraphSample()
+		lifeRenderer.Raster().Resize(moverWidget.Size())
+		lifeRenderer.Draw(lifeGen, LifeView{X: -xOffset, Y: -yOffset, CellSize: gridSize}, moverWidget.Size())
+		if pg := pasteGen; pg != nil {
+			pasteRenderer.Raster().Resize(moverWidget.Size())
+			pasteRenderer.Draw(pg, LifeView{X: -xOffset - float64(cursorCellX), Y: -yOffset - float64(cursorCellY), CellSize: gridSize}, moverWidget.Size())
+		} else if tg := toolGen; tg != nil {
+			pasteRenderer.Raster().Resize(moverWidget.Size())
+			pasteRenderer.Draw(tg, LifeView{X: -xOffset, Y: -yOffset, CellSize: gridSize}, moverWidget.Size())
+		}
+		if lifeMinimap.Raster().Visible() {
+			lifeMinimap.Raster().Move(fyne.Position{X: moverWidget.Size().Width - MINIMAP_WIDTH - MINIMAP_MARGIN, Y: MINIMAP_MARGIN})
+			lifeMinimap.Draw(lifeGen, LifeView{X: -xOffset, Y: -yOffset, CellSize: gridSize}, moverWidget.Size())
 		}
 		timeText.SetText(fmt.Sprintf("Delay: %03dms Time: %05dms Gen: %05d Cells:%05d", lifeController.GetAnimationDelay(), lifeGen.GetGenerationTime(), lifeGen.GetGenerationCount(), lifeGen.GetCellCount()))
 		return false
 	})
+	moverWidget.AddBottom(lifeRenderer.Raster())
+	moverWidget.AddBottom(pasteRenderer.Raster())
 	moverWidget.AddTop(targetDot)
 	moverWidget.AddTop(targetRect)
+	moverWidget.AddTop(lifeMinimap.Raster())
 	moverWidget.SetFileBrowserWidget(fbWidget)
 
 	topV.Add(topC)
 	saveContainer.Add(fbWidget.InputSaveForm("Save Selected Cells to a RLE File"))
-	saveContainer.Add(widget.NewForm(widget.NewFormItem("Name of Owner :", ownerEntry), widget.NewFormItem("Description :", descriptionEntry)))
+	saveRleForm = widget.NewForm(widget.NewFormItem("Name of Owner :", ownerEntry), widget.NewFormItem("Description :", descriptionEntry), widget.NewFormItem("", saveCanonical))
+	saveContainer.Add(saveRleForm)
+	exportForm = widget.NewForm(widget.NewFormItem("Cell size :", exportSizeEntry), widget.NewFormItem("Colours :", exportScheme), widget.NewFormItem("Generations (gif) :", exportGensEntry), widget.NewFormItem("", exportGrid))
+	exportGensEntry.SetText("100")
+	exportForm.Hide()
+	saveContainer.Add(exportForm)
 	saveContainer.Hide()
 	topV.Add(saveContainer)
-	topV.Add(errorContainer.container)
-	return container.NewBorder(topV, botC, nil, nil, moverWidget)
-}
-
-func POCLifeResetDot() {
-	dotsPos = 0
-	for i := 0; i < len(dots); i++ {
-		dots[i].Hide()
+	apgEntry.PlaceHolder = "Enter an apgcode. For example xq4_153. Paste stamps it at the cursor"
+	apgEntry.OnSubmitted = func(s string) {
+		POCLifeApgPaste()
 	}
-}
-
-func POCLifeGetDot(x, y int64, mode int, moverWidget *MoverWidget) {
-	if dotsPos >= len(dots) {
-		for i := 0; i < 20; i++ {
-			d := canvas.NewCircle(color.RGBA{0, 0, 255, 255})
-			d.Hide()
-			dots = append(dots, d)
-			moverWidget.AddBottom(d)
+	apgContainer = container.New(NewEntryLayout(100, 40), widget.NewLabel("ApgCode:"), apgEntry, widget.NewButton("Cancel", func() {
+		apgContainer.Hide()
+	}), widget.NewButton("Paste", POCLifeApgPaste))
+	apgContainer.Hide()
+	topV.Add(apgContainer)
+	ruleEntry.PlaceHolder = "Enter a rule in B/S notation. For example B36/S23"
+	ruleEntry.Validator = func(s string) error {
+		_, err := ParseLifeRule(s)
+		return err
+	}
+	ruleEntry.OnSubmitted = func(s string) {
+		POCLifeRuleApply()
+	}
+	ruleNamed.PlaceHolder = "Named rules"
+	ruleNamed.OnChanged = func(s string) {
+		if rule, err := LifeRuleByName(s); err == nil {
+			ruleEntry.SetText(rule.String())
 		}
+		lifeWindow.Canvas().Focus(ruleEntry)
 	}
-	dot := dots[dotsPos]
-	dotsPos++
-	//
-	//	posX, posY := cellToScreen(x, y)
-	//
-	posX := float32((xOffset + x) * gridSize)
-	posY := float32((yOffset + y) * gridSize)
-	dot.Position1 = fyne.Position{X: posX, Y: posY}
-	dot.Position2 = fyne.Position{X: posX + float32(gridSize), Y: posY + float32(gridSize)}
-	dot.FillColor = COLOURS[mode&COLOUR_MODE_MASK]
-	dot.Resize(fyne.Size{Width: float32(gridSize), Height: float32(gridSize)})
-	dot.Show()
+	ruleContainer = container.New(NewEntryLayout(100, 40), widget.NewLabel("Rule:"), ruleEntry, ruleNamed, widget.NewButton("Cancel", func() {
+		ruleContainer.Hide()
+	}), widget.NewButton("Apply", POCLifeRuleApply))
+	ruleContainer.Hide()
+	topV.Add(ruleContainer)
+	findEntry.PlaceHolder = "Search. For example: glider kind:spaceship rule:B3/S23 size<10 pop>=5 period:4"
+	findEntry.OnSubmitted = func(s string) {
+		POCLifeFind()
+	}
+	findList = widget.NewList(func() int {
+		return len(findResults)
+	}, func() fyne.CanvasObject {
+		return widget.NewLabel("")
+	}, func(id widget.ListItemID, o fyne.CanvasObject) {
+		o.(*widget.Label).SetText(findResults[id].String())
+	})
+	findList.OnSelected = func(id widget.ListItemID) {
+		if id < len(findResults) && POCLifeLoadFile(findIndex.FullPath(findResults[id]), int64(xOffset), int64(yOffset), true) == nil {
+			findContainer.Hide()
+		}
+	}
+	findListSize := canvas.NewRectangle(color.Transparent)
+	findListSize.SetMinSize(fyne.NewSize(100, 200))
+	findContainer = container.NewVBox(container.New(NewEntryLayout(100, 40), widget.NewLabel("Find:"), findEntry, findLabel, widget.NewButton("Cancel", func() {
+		findContainer.Hide()
+	}), widget.NewButton("Search", POCLifeFind)), container.NewMax(findListSize, findList))
+	findContainer.Hide()
+	topV.Add(findContainer)
+	topV.Add(errorContainer.container)
+	return container.NewBorder(topV, botC, nil, nil, moverWidget)
 }
 
 func lifeCellToScreen(cellX, cellY int64) (float32, float32) {
-	x := ((xOffset + cellX) * gridSize)
-	y := ((yOffset + cellY) * gridSize)
+	x := (xOffset + float64(cellX)) * gridSize
+	y := (yOffset + float64(cellY)) * gridSize
 	return float32(x), float32(y)
 }
 
 func lifeScreenToCell(mouseX, mouseY float32) (int64, int64) {
-	cellX := int64((mouseX / float32(gridSize))) - xOffset
-	cellY := int64((mouseY / float32(gridSize))) - yOffset
+	cellX := int64(math.Floor(float64(mouseX)/gridSize - xOffset))
+	cellY := int64(math.Floor(float64(mouseY)/gridSize - yOffset))
 	return cellX, cellY
 }
 
+// The cursor is at least 2 units so it can be seen when zoomed out
+func lifeDotSize() float32 {
+	return float32(math.Max(2, gridSize))
+}
+
 func lifeSeperator() *widget.Separator {
 	sep := widget.NewSeparator()
 	sep.Resize(fyne.Size{Width: 10, Height: sep.MinSize().Height})
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+)
+
+const (
+	MINIMAP_WIDTH  = 160 // Size of the minimap in Fyne units
+	MINIMAP_HEIGHT = 120
+	MINIMAP_MARGIN = 10 // Space between the minimap and the edge of the Life view
+	MINIMAP_BORDER = 4  // Cells of space around the universe inside the minimap
+)
+
+/*
+A small overview of the whole universe drawn in to a single image shown by a canvas.Raster.
+
+	The area shown is the bounds of all the cells (see GetBounds) plus the area in the view so the view rectangle is always on the map.
+	Each pixel is shaded by the number of cells that fall in it so sparse and dense areas can be told apart.
+	The mapping from pixels to cells is kept from the last draw so a click on the map can be turned in to a cell position.
+*/
+type LifeMinimap struct {
+	mu         sync.Mutex
+	raster     *canvas.Raster
+	front      *image.RGBA
+	back       *image.RGBA
+	pixW       int // Size in pixels requested by the raster. 0 until the first paint
+	pixH       int
+	Background color.RGBA
+	Cell       color.RGBA // Colour of the densest pixel. Others are a blend of this and the background
+	ViewColour color.RGBA // The outline of the view
+	scale      float64    // Pixels per unit. Pixels are not the same as Fyne units on high DPI screens
+	mapping    MinimapMapping
+}
+
+// How cells map on to the pixels of the minimap. Pixel = (Cell - X) * CellSize
+type MinimapMapping struct {
+	X, Y     float64
+	CellSize float64
+}
+
+func NewLifeMinimap() *LifeMinimap {
+	mm := &LifeMinimap{
+		Background: color.RGBA{0, 0, 0, 200},
+		Cell:       color.RGBA{0, 255, 255, 255},
+		ViewColour: color.RGBA{255, 0, 0, 255},
+		scale:      1,
+	}
+	mm.raster = canvas.NewRaster(func(w, h int) image.Image {
+		mm.mu.Lock()
+		defer mm.mu.Unlock()
+		mm.pixW, mm.pixH = w, h
+		if mm.front == nil {
+			return image.NewRGBA(image.Rect(0, 0, w, h))
+		}
+		return mm.front
+	})
+	mm.raster.Resize(fyne.Size{Width: MINIMAP_WIDTH, Height: MINIMAP_HEIGHT})
+	return mm
+}
+
+func (mm *LifeMinimap) Raster() *canvas.Raster {
+	return mm.raster
+}
+
+/*
+Draw the universe and the view. view is the cell at the top left of the Life view and its cell size.
+viewSize is the size of the Life view in Fyne units.
+*/
+func (mm *LifeMinimap) Draw(lg *LifeGen, view LifeView, viewSize fyne.Size) {
+	mm.mu.Lock()
+	w, h := mm.pixW, mm.pixH
+	mm.mu.Unlock()
+	if w == 0 || h == 0 {
+		w, h = MINIMAP_WIDTH, MINIMAP_HEIGHT
+	}
+	if mm.back == nil || mm.back.Rect.Dx() != w || mm.back.Rect.Dy() != h {
+		mm.back = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+	mapping := mm.RenderImage(mm.back, lg, view, float64(viewSize.Width)/view.CellSize, float64(viewSize.Height)/view.CellSize)
+	mm.mu.Lock()
+	mm.front, mm.back = mm.back, mm.front
+	mm.mapping = mapping
+	mm.scale = float64(w) / MINIMAP_WIDTH
+	mm.mu.Unlock()
+	mm.raster.Refresh()
+}
+
+/*
+Clear the image and draw the density of the cells and the outline of the view.
+viewW and viewH are the size of the view in cells. Returns the mapping used.
+*/
+func (mm *LifeMinimap) RenderImage(img *image.RGBA, lg *LifeGen, view LifeView, viewW, viewH float64) MinimapMapping {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	for i := 0; i < len(img.Pix); i = i + 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = mm.Background.R, mm.Background.G, mm.Background.B, mm.Background.A
+	}
+	mapping := MinimapMappingFor(lg, view, viewW, viewH, w, h)
+	counts := make([]int, w*h)
+	most := 0
+	lg.CellsInBounds(int64(math.Floor(mapping.X)), int64(math.Floor(mapping.Y)), int64(math.Ceil(mapping.X+float64(w)/mapping.CellSize)), int64(math.Ceil(mapping.Y+float64(h)/mapping.CellSize)), func(lc *LifeCell) {
+		px, py := mapping.ToPixel(float64(lc.x), float64(lc.y))
+		if px < 0 || py < 0 || px >= w || py >= h {
+			return
+		}
+		i := py*w + px
+		counts[i]++
+		if counts[i] > most {
+			most = counts[i]
+		}
+	})
+	if most > 0 {
+		// A log scale so a single cell can still be seen next to a dense area
+		lm := math.Log(float64(most) + 1)
+		for i, n := range counts {
+			if n > 0 {
+				f := 0.3 + 0.7*math.Log(float64(n)+1)/lm
+				img.SetRGBA(i%w, i/w, minimapBlend(mm.Background, mm.Cell, f))
+			}
+		}
+	}
+	x1, y1 := mapping.ToPixel(view.X, view.Y)
+	x2, y2 := mapping.ToPixel(view.X+viewW, view.Y+viewH)
+	minimapOutline(img, x1, y1, x2, y2, mm.ViewColour)
+	return mapping
+}
+
+/*
+The mapping that fits the bounds of the cells and the view in to a w x h image, centred, with the same scale for x and y.
+viewW and viewH are the size of the view in cells.
+*/
+func MinimapMappingFor(lg *LifeGen, view LifeView, viewW, viewH float64, w, h int) MinimapMapping {
+	x1, y1, x2, y2 := view.X, view.Y, view.X+viewW, view.Y+viewH
+	if lg.GetRootCell() != nil {
+		bx1, by1, bx2, by2 := lg.GetBounds()
+		x1 = math.Min(x1, float64(bx1))
+		y1 = math.Min(y1, float64(by1))
+		x2 = math.Max(x2, float64(bx2+1))
+		y2 = math.Max(y2, float64(by2+1))
+	}
+	x1, y1, x2, y2 = x1-MINIMAP_BORDER, y1-MINIMAP_BORDER, x2+MINIMAP_BORDER, y2+MINIMAP_BORDER
+	cs := math.Min(float64(w)/(x2-x1), float64(h)/(y2-y1))
+	// Centre the area in the image
+	return MinimapMapping{
+		X:        x1 - (float64(w)/cs-(x2-x1))/2,
+		Y:        y1 - (float64(h)/cs-(y2-y1))/2,
+		CellSize: cs,
+	}
+}
+
+func (m MinimapMapping) ToPixel(x, y float64) (int, int) {
+	return int(math.Floor((x - m.X) * m.CellSize)), int(math.Floor((y - m.Y) * m.CellSize))
+}
+
+func (m MinimapMapping) ToCell(px, py float64) (float64, float64) {
+	return px/m.CellSize + m.X, py/m.CellSize + m.Y
+}
+
+/*
+The cell under a position on the minimap from the last draw. x, y are in Fyne units relative to the top left of the minimap.
+*/
+func (mm *LifeMinimap) CellAt(x, y float32) (float64, float64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if mm.mapping.CellSize <= 0 {
+		return 0, 0
+	}
+	return mm.mapping.ToCell(float64(x)*mm.scale, float64(y)*mm.scale)
+}
+
+// Blend from a to b. f is 0 for a and 1 for b
+func minimapBlend(a, b color.RGBA, f float64) color.RGBA {
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*f)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// Draw the outline of a rectangle. Parts outside the image are skipped. x2, y2 are outside the rectangle
+func minimapOutline(img *image.RGBA, x1, y1, x2, y2 int, c color.RGBA) {
+	if x2 <= x1 {
+		x2 = x1 + 1
+	}
+	if y2 <= y1 {
+		y2 = y1 + 1
+	}
+	for x := x1; x < x2; x++ {
+		minimapSet(img, x, y1, c)
+		minimapSet(img, x, y2-1, c)
+	}
+	for y := y1; y < y2; y++ {
+		minimapSet(img, x1, y, c)
+		minimapSet(img, x2-1, y, c)
+	}
+}
+
+func minimapSet(img *image.RGBA, x, y int, c color.RGBA) {
+	if (image.Point{x, y}).In(img.Rect) {
+		img.SetRGBA(x, y, c)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestMinimapImage(t *testing.T) {
+	lg := NewLifeGen(nil, 0)
+	lg.AddCellsAtOffset(0, 0, 0, []int64{0, 4, 1, 4, 0, 5, 1, 5, 99, 4})
+	mm := NewLifeMinimap()
+	view := LifeView{X: 10, Y: 0, CellSize: 10}
+
+	// Bounds with the border are -4,-4 to 104,14. The height is centred in the image
+	img := image.NewRGBA(image.Rect(0, 0, 108, 54))
+	m := mm.RenderImage(img, lg, view, 20, 10)
+	if m.X != -4 || m.Y != -22 || m.CellSize != 1 {
+		t.Fatalf("mapping is wrong %+v", m)
+	}
+	AssertPixel(t, img, 4, 26, mm.Cell, "cell 0,4")
+	AssertPixel(t, img, 103, 26, mm.Cell, "cell 99,4")
+	AssertPixel(t, img, 50, 26, mm.Background, "no cell 46,4")
+	AssertPixel(t, img, 14, 22, mm.ViewColour, "top left of the view")
+	AssertPixel(t, img, 33, 31, mm.ViewColour, "bottom right of the view")
+	AssertPixel(t, img, 20, 25, mm.Background, "inside the view")
+	if x, y := m.ToCell(4, 26); x != 0 || y != 4 {
+		t.Errorf("pixel 4,26 is cell %f,%f expected 0,4", x, y)
+	}
+
+	// Half the size. The block of 4 cells is in one pixel and is brighter than the single cell
+	img = image.NewRGBA(image.Rect(0, 0, 54, 27))
+	mm.RenderImage(img, lg, view, 20, 10)
+	AssertPixel(t, img, 2, 13, mm.Cell, "block at 0,4")
+	AssertPixel(t, img, 51, 13, minimapBlend(mm.Background, mm.Cell, 0.3+0.7*math.Log(2)/math.Log(5)), "cell 99,4")
+}
+
+func TestMinimapMappingEmpty(t *testing.T) {
+	// No cells so only the view is shown
+	m := MinimapMappingFor(NewLifeGen(nil, 0), LifeView{X: 10, Y: 10, CellSize: 1}, 40, 20, 96, 56)
+	if m.X != 6 || m.Y != 6 || m.CellSize != 2 {
+		t.Errorf("mapping is wrong %+v", m)
+	}
+}
@@ -22,6 +22,7 @@ type MoverWidget struct {
 	onMouseEvent      func(*MoverWidgetMouseEvent)
 	onMouseMask       MoverMouseEventType
 	mouseDown         bool
+	mouseButton       int
 	mouseDownX        int64
 	mouseDownY        int64
 	mouseDragX        int64
@@ -40,6 +41,7 @@ const (
 	MM_ME_MOUT MoverMouseEventType = 0b0000000000100000
 	MM_ME_MOVE MoverMouseEventType = 0b0000000001000000
 	MM_ME_DRAG MoverMouseEventType = 0b0000000010000000
+	MM_ME_SCRL MoverMouseEventType = 0b0000000100000000 // Mouse wheel or track pad scroll. See ScrollX, ScrollY
 )
 
 type MoverWidgetMouseEvent struct {
@@ -50,6 +52,8 @@ type MoverWidgetMouseEvent struct {
 	Event    MoverMouseEventType
 	Button   int
 	Dragging bool
+	ScrollX  float32
+	ScrollY  float32
 }
 
 func NewMoverWidgetMouseEvent(me *desktop.MouseEvent, et MoverMouseEventType) *MoverWidgetMouseEvent {
@@ -104,6 +108,7 @@ func (me *MoverWidgetMouseEvent) Size() *fyne.Size {
 var _ desktop.Mouseable = (*MoverWidget)(nil)
 var _ fyne.Tappable = (*MoverWidget)(nil)
 var _ fyne.DoubleTappable = (*MoverWidget)(nil)
+var _ fyne.Scrollable = (*MoverWidget)(nil)
 var _ fyne.WidgetRenderer = (*moverWidgetRenderer)(nil)
 var _ fyne.Widget = (*MoverWidget)(nil)
 
@@ -184,6 +189,7 @@ func (mc *MoverWidget) MouseMoved(me *desktop.MouseEvent) {
 		mwme := NewMoverWidgetMouseEvent(me, MM_ME_MOVE)
 		mwme.Dragging = mc.mouseDown
 		if mc.mouseDown {
+			mwme.Button = mc.mouseButton
 			mc.mouseDragX = mwme.X1
 			mc.mouseDragY = mwme.Y1
 			mwme.X2 = mc.mouseDragX
@@ -210,6 +216,7 @@ func (mc *MoverWidget) MouseDown(me *desktop.MouseEvent) {
 		mc.mouseDragX = mwme.X1
 		mc.mouseDragY = mwme.Y1
 		mc.mouseDown = true
+		mc.mouseButton = mwme.Button
 		mc.onMouseEvent(mwme)
 	}
 }
@@ -243,6 +250,16 @@ func (mc *MoverWidget) DoubleTapped(me *fyne.PointEvent) {
 	}
 }
 
+// Scrolled is called for the mouse wheel and track pad scrolling. DY is 10 for each click of the wheel
+func (mc *MoverWidget) Scrolled(se *fyne.ScrollEvent) {
+	if mc.onMouseEvent != nil && (mc.onMouseMask&MM_ME_SCRL) != 0 {
+		mwme := NewMoverWidgetPointEvent(&se.PointEvent, MM_ME_SCRL)
+		mwme.ScrollX = se.Scrolled.DX
+		mwme.ScrollY = se.Scrolled.DY
+		mc.onMouseEvent(mwme)
+	}
+}
+
 // Widget Renderer code starts here
 type moverWidgetRenderer struct {
 	moverWidget *MoverWidget // Reference to the widget holding the current state
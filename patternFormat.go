@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type PatternFormat int
+
+// The pattern file formats that can be loaded and saved.
+// All formats are loaded in to an RLE struct.
+const (
+	PATTERN_RLE       PatternFormat = 0 // x = 3, y = 1, rule = B3/S23 then 3o!
+	PATTERN_PLAINTEXT PatternFormat = 1 // !Name: then rows of '.' and 'O'
+	PATTERN_LIFE_106  PatternFormat = 2 // #Life 1.06 then one 'x y' line per cell
+
+	LIFE_106_HEADER = "#Life 1.06"
+)
+
+type patternFormatDef struct {
+	name       string
+	extensions []string // The first one is used when saving
+}
+
+var patternFormats = []*patternFormatDef{
+	{name: "rle", extensions: []string{".rle"}},
+	{name: "cells", extensions: []string{".cells"}},
+	{name: "life106", extensions: []string{".lif", ".life"}},
+}
+
+func (f PatternFormat) String() string {
+	if f < 0 || int(f) >= len(patternFormats) {
+		return fmt.Sprintf("format(%d)", int(f))
+	}
+	return patternFormats[f].name
+}
+
+// The extension used when saving in this format. For example '.cells'
+func (f PatternFormat) Ext() string {
+	return patternFormats[f].extensions[0]
+}
+
+// Parse a format name (rle, cells or life106) or an extension (.rle .cells .lif .life)
+func ParsePatternFormat(name string) (PatternFormat, error) {
+	n := strings.ToLower(strings.TrimSpace(name))
+	for i, f := range patternFormats {
+		if f.name == n {
+			return PatternFormat(i), nil
+		}
+		for _, e := range f.extensions {
+			if e == n || e[1:] == n {
+				return PatternFormat(i), nil
+			}
+		}
+	}
+	return PATTERN_RLE, fmt.Errorf("unknown pattern format '%s'. Use one of %s", name, strings.Join(PatternFormatNames(), ","))
+}
+
+func PatternFormatNames() []string {
+	resp := make([]string, len(patternFormats))
+	for i, f := range patternFormats {
+		resp[i] = f.name
+	}
+	return resp
+}
+
+// Find the format of a file from its extension
+func PatternFormatForFile(fileName string) (PatternFormat, error) {
+	ext := filepath.Ext(fileName)
+	if ext == "" {
+		return PATTERN_RLE, fmt.Errorf("file '%s' has no extension. Cannot tell its pattern format", fileName)
+	}
+	f, err := ParsePatternFormat(ext)
+	if err != nil {
+		return PATTERN_RLE, fmt.Errorf("file '%s' is not a pattern file. Use one of %s", fileName, strings.Join(PatternFormatNames(), ","))
+	}
+	return f, nil
+}
+
+// Return true if the file name has the extension of a supported pattern format
+func IsPatternFile(fileName string) bool {
+	_, err := PatternFormatForFile(fileName)
+	return err == nil
+}
+
+// Load a pattern file in any supported format. The format is taken from the file extension.
+func LoadPattern(fileName string) (*RLE, error) {
+	format, err := PatternFormatForFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if format == PATTERN_RLE {
+		return NewRleFile(fileName)
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return NewPatternReader(file, fileName, format)
+}
+
+// Read a pattern in the given format from any reader. fileName is used to identify the source in errors.
+func NewPatternReader(r io.Reader, fileName string, format PatternFormat) (*RLE, error) {
+	switch format {
+	case PATTERN_PLAINTEXT:
+		return newPlaintextReader(r, fileName)
+	case PATTERN_LIFE_106:
+		return newLife106Reader(r, fileName)
+	}
+	return NewRleReader(r, fileName)
+}
+
+// Plaintext. Lines starting '!' are comments. '!Name:' and '!Author:' set the name and owner.
+// Other lines are rows of cells where '.' is dead and 'O' (or '*') is alive.
+func newPlaintextReader(r io.Reader, fileName string) (*RLE, error) {
+	rle := &RLE{fileName: fileName, rule: RLE_DEFAULT_RULE}
+	coords := make([]int64, 0)
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	var y int64 = 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "!") {
+			text := strings.TrimSpace(line[1:])
+			switch {
+			case strings.HasPrefix(text, "Name:"):
+				rle.name = strings.TrimSpace(text[5:])
+			case strings.HasPrefix(text, "Author:"):
+				rle.owner = strings.TrimSpace(text[7:])
+			default:
+				if rle.comment == "" {
+					rle.comment = text
+				}
+				rle.comments = append(rle.comments, text)
+			}
+			continue
+		}
+		for x, c := range line {
+			switch c {
+			case '.':
+			case 'O', 'o', '*':
+				coords = append(coords, int64(x), y)
+			default:
+				return nil, &RLEError{FileName: fileName, Line: lineNo, Col: x + 1, Msg: fmt.Sprintf("unexpected character '%c'. Expected '.' or 'O'", c)}
+			}
+		}
+		y++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	rle.SetCoords(coords)
+	return rle, nil
+}
+
+// Life 1.06. The first line is '#Life 1.06' then each line is the 'x y' of a live cell.
+// Other lines starting '#' are comments. '#N' and '#O' set the name and owner as in RLE.
+func newLife106Reader(r io.Reader, fileName string) (*RLE, error) {
+	rle := &RLE{fileName: fileName, rule: RLE_DEFAULT_RULE}
+	coords := make([]int64, 0)
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNo == 1 {
+			if line != LIFE_106_HEADER {
+				return nil, &RLEError{FileName: fileName, Line: 1, Col: 1, Msg: fmt.Sprintf("first line must be '%s'", LIFE_106_HEADER)}
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if line[0] == '#' {
+			text := ""
+			if len(line) > 2 {
+				text = strings.TrimSpace(line[2:])
+			}
+			switch {
+			case strings.HasPrefix(line, "#N"):
+				rle.name = text
+			case strings.HasPrefix(line, "#O"):
+				rle.owner = text
+			case strings.HasPrefix(line, "#C") || strings.HasPrefix(line, "#D"):
+				if rle.comment == "" {
+					rle.comment = text
+				}
+				rle.comments = append(rle.comments, text)
+			}
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			return nil, &RLEError{FileName: fileName, Line: lineNo, Col: 1, Msg: fmt.Sprintf("line '%s' is not in the form 'x y'", line)}
+		}
+		x, errX := strconv.ParseInt(parts[0], 10, 64)
+		y, errY := strconv.ParseInt(parts[1], 10, 64)
+		if errX != nil || errY != nil {
+			return nil, &RLEError{FileName: fileName, Line: lineNo, Col: 1, Msg: fmt.Sprintf("line '%s' x and y must be numbers", line)}
+		}
+		coords = append(coords, x, y)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lineNo == 0 {
+		return nil, &RLEError{FileName: fileName, Line: 1, Col: 1, Msg: fmt.Sprintf("file is empty. Expected '%s'", LIFE_106_HEADER)}
+	}
+	rle.SetCoords(coords)
+	return rle, nil
+}
+
+// Replace the cells. The bounds, encoded cells and header width and height are updated.
+func (rle *RLE) SetCoords(coords []int64) {
+	rle.coords = coords
+	rle.decoded = ""
+	rle.minX, rle.minY, rle.maxX, rle.maxY = 0, 0, 0, 0
+	if len(coords) > 0 {
+		rle.minX, rle.minY = apgMinXY(coords)
+		rle.maxX, rle.maxY = coords[0], coords[1]
+		for i := 2; i < len(coords); i = i + 2 {
+			rle.maxX = maxInt64(rle.maxX, coords[i])
+			rle.maxY = maxInt64(rle.maxY, coords[i+1])
+		}
+	}
+	rle.encoded, rle.width, rle.height = rle.Encode()
+}
+
+// The content of a file for the pattern in the given format
+func (rle *RLE) FileContent(format PatternFormat) string {
+	switch format {
+	case PATTERN_PLAINTEXT:
+		return rle.plaintextContent()
+	case PATTERN_LIFE_106:
+		return rle.life106Content()
+	}
+	return rle.SaveFileContent()
+}
+
+func (rle *RLE) plaintextContent() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("!Name: %s\n", rle.name))
+	if rle.owner != "" {
+		sb.WriteString(fmt.Sprintf("!Author: %s\n", rle.owner))
+	}
+	for _, c := range rle.Comments() {
+		sb.WriteString(fmt.Sprintf("!%s\n", c))
+	}
+	if len(rle.coords) == 0 {
+		return sb.String()
+	}
+	co, _, h := POCNormaliseCoords(rle.coords)
+	rows := make([][]byte, h)
+	for i := 0; i < len(co); i = i + 2 {
+		row := rows[co[i+1]]
+		for int64(len(row)) <= co[i] {
+			row = append(row, '.')
+		}
+		row[co[i]] = 'O'
+		rows[co[i+1]] = row
+	}
+	for _, row := range rows {
+		sb.Write(row)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func (rle *RLE) life106Content() string {
+	var sb strings.Builder
+	sb.WriteString(LIFE_106_HEADER)
+	sb.WriteString("\n")
+	cells := make([][2]int64, len(rle.coords)/2)
+	for i := 0; i < len(rle.coords); i = i + 2 {
+		cells[i/2] = [2]int64{rle.coords[i], rle.coords[i+1]}
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i][1] != cells[j][1] {
+			return cells[i][1] < cells[j][1]
+		}
+		return cells[i][0] < cells[j][0]
+	})
+	for _, c := range cells {
+		sb.WriteString(fmt.Sprintf("%d %d\n", c[0], c[1]))
+	}
+	return sb.String()
+}
+
+// Save the pattern to a file in the given format
+func (rle *RLE) SaveAs(fileName string, format PatternFormat) error {
+	return os.WriteFile(fileName, []byte(rle.FileContent(format)), 0644)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testGliderCells = "!Name: Glider\n!Author: Richard K. Guy\n!The smallest spaceship\n.O\n..O\nOOO\n"
+	testGliderLife  = "#Life 1.06\n1 0\n2 1\n0 2\n1 2\n2 2\n"
+)
+
+func TestPatternFormatParse(t *testing.T) {
+	for name, exp := range map[string]PatternFormat{"rle": PATTERN_RLE, ".RLE": PATTERN_RLE, "cells": PATTERN_PLAINTEXT, ".lif": PATTERN_LIFE_106, "life": PATTERN_LIFE_106, "life106": PATTERN_LIFE_106} {
+		f, err := ParsePatternFormat(name)
+		if err != nil || f != exp {
+			t.Errorf("format '%s' returned %s expected %s", name, f, exp)
+		}
+	}
+	_, err := ParsePatternFormat("mc")
+	if err == nil || err.Error() != "unknown pattern format 'mc'. Use one of rle,cells,life106" {
+		t.Errorf("format 'mc' should fail. Got %v", err)
+	}
+	if IsPatternFile("times.txt") || !IsPatternFile("a/b.Cells") {
+		t.Errorf("IsPatternFile returned the wrong result")
+	}
+}
+
+func TestPatternLoadFormats(t *testing.T) {
+	rle := AssertPatternRead(t, testGliderCells, PATTERN_PLAINTEXT, "bo$2bo$3o!")
+	if rle.name != "Glider" || rle.owner != "Richard K. Guy" || rle.comment != "The smallest spaceship" {
+		t.Errorf("plaintext headers not read. %s", rle.String())
+	}
+	if rle.FileContent(PATTERN_PLAINTEXT) != testGliderCells {
+		t.Errorf("plaintext content should match the input. Got:\n%s", rle.FileContent(PATTERN_PLAINTEXT))
+	}
+	if rle.FileContent(PATTERN_LIFE_106) != testGliderLife {
+		t.Errorf("life 1.06 content is wrong. Got:\n%s", rle.FileContent(PATTERN_LIFE_106))
+	}
+
+	rle = AssertPatternRead(t, "#Life 1.06\n#N Blinker\n-1 5\n0 5\n\n1 5\n", PATTERN_LIFE_106, "3o!")
+	if rle.name != "Blinker" || rle.minX != -1 || rle.minY != 5 || rle.width != 3 || rle.height != 1 {
+		t.Errorf("life 1.06 bounds or name wrong. %s", rle.String())
+	}
+	if rle.FileContent(PATTERN_LIFE_106) != "#Life 1.06\n-1 5\n0 5\n1 5\n" {
+		t.Errorf("life 1.06 should keep the position. Got:\n%s", rle.FileContent(PATTERN_LIFE_106))
+	}
+	AssertPatternRead(t, "x = 3, y = 1\n3o!", PATTERN_RLE, "3o!")
+
+	AssertPatternReadError(t, ".O\n.X\n", PATTERN_PLAINTEXT, "test:2:2: unexpected character 'X'. Expected '.' or 'O'")
+	AssertPatternReadError(t, "#Life 1.05\n", PATTERN_LIFE_106, "test:1:1: first line must be '#Life 1.06'")
+	AssertPatternReadError(t, "", PATTERN_LIFE_106, "test:1:1: file is empty. Expected '#Life 1.06'")
+	AssertPatternReadError(t, "#Life 1.06\n1 2 3\n", PATTERN_LIFE_106, "test:2:1: line '1 2 3' is not in the form 'x y'")
+	AssertPatternReadError(t, "#Life 1.06\n1 a\n", PATTERN_LIFE_106, "test:2:1: line '1 a' x and y must be numbers")
+}
+
+func TestConvertPattern(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "glider.cells")
+	os.WriteFile(in, []byte(testGliderCells), 0644)
+
+	out := filepath.Join(dir, "glider.lif")
+	if err := ConvertPattern(in, out, &ConvertOptions{Format: PATTERN_LIFE_106}); err != nil {
+		t.Fatalf("convert failed %s", err.Error())
+	}
+	AssertFileContent(t, out, testGliderLife)
+
+	// A canonical pattern is its own canonical form
+	out = filepath.Join(dir, "glider.rle")
+	if err := ConvertPattern(in, out, &ConvertOptions{Format: PATTERN_RLE, Canonical: true, Name: "G", Comment: "canon"}); err != nil {
+		t.Fatalf("convert failed %s", err.Error())
+	}
+	rle, err := LoadPattern(out)
+	if err != nil {
+		t.Fatalf("converted file did not load %s", err.Error())
+	}
+	if _, sym := CanonicalCoords(rle.coords); sym != SYM_NONE {
+		t.Errorf("converted glider should be canonical. Got %s", sym)
+	}
+	b, _ := os.ReadFile(out)
+	if rle.name != "G" || rle.owner != "Richard K. Guy" || !strings.Contains(string(b), "\n#C canon\n") {
+		t.Errorf("converted headers are wrong. %s", string(b))
+	}
+}
+
+func TestConvertCanonicalMatchesSave(t *testing.T) {
+	dir := t.TempDir()
+	gun, err := LoadPattern("testdata/GliderGun.rle")
+	if err != nil {
+		t.Fatalf("load failed %s", err.Error())
+	}
+	// Rotate the pattern so it is not already canonical
+	rotated, _, _ := TransformCoords(gun.coords, SYM_ROT_90)
+	in := filepath.Join(dir, "rotated.rle")
+	if err := NewRLESave(in, rotated, "", "").Save(); err != nil {
+		t.Fatalf("save failed %s", err.Error())
+	}
+	out := filepath.Join(dir, "converted.rle")
+	if err := ConvertPattern(in, out, &ConvertOptions{Format: PATTERN_RLE, Canonical: true}); err != nil {
+		t.Fatalf("convert failed %s", err.Error())
+	}
+	converted, _ := LoadPattern(out)
+	// As the GUI saves with Canonicalise checked
+	canon, _ := CanonicalCoords(rotated)
+	saved := NewRLESave(filepath.Join(dir, "saved.rle"), canon, "", "")
+	if converted.encoded != saved.encoded || converted.width != saved.width || converted.height != saved.height {
+		t.Errorf("convert -canonical and the GUI save are different.\n%s\n%s", converted.encoded, saved.encoded)
+	}
+}
+
+func TestConvertPatternComments(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "blinker.rle")
+	content := "#N Blinker\n#O John Conway\n#C Period 2\n#C The most common oscillator\n#C https://conwaylife.com/wiki/Blinker\nx = 3, y = 1, rule = B3/S23\n3o!\n"
+	os.WriteFile(in, []byte(content), 0644)
+	// RLE to RLE does not lose anything
+	out := filepath.Join(dir, "out.rle")
+	if err := ConvertPattern(in, out, &ConvertOptions{Format: PATTERN_RLE}); err != nil {
+		t.Fatalf("convert failed %s", err.Error())
+	}
+	AssertFileContent(t, out, content)
+	out = filepath.Join(dir, "out.cells")
+	if err := ConvertPattern(in, out, &ConvertOptions{Format: PATTERN_PLAINTEXT}); err != nil {
+		t.Fatalf("convert failed %s", err.Error())
+	}
+	AssertFileContent(t, out, "!Name: Blinker\n!Author: John Conway\n!Period 2\n!The most common oscillator\n!https://conwaylife.com/wiki/Blinker\nOOO\n")
+	// And back again
+	out2 := filepath.Join(dir, "out2.rle")
+	if err := ConvertPattern(out, out2, &ConvertOptions{Format: PATTERN_RLE}); err != nil {
+		t.Fatalf("convert failed %s", err.Error())
+	}
+	AssertFileContent(t, out2, content)
+}
+
+func TestCliConvertDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "glider.cells"), []byte(testGliderCells), 0644)
+	os.WriteFile(filepath.Join(dir, "blinker.lif"), []byte("#Life 1.06\n5 5\n6 5\n7 5\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a pattern"), 0644)
+	outDir := filepath.Join(dir, "out")
+	stdout := AssertCliCommand(t, []string{"convert", "-in", dir, "-out", outDir, "-format", "life106", "-normalise"}, CLI_EXIT_OK)
+	if !strings.HasSuffix(stdout, "Converted 2 file(s). 0 failed\n") {
+		t.Errorf("convert output is wrong:\n%s", stdout)
+	}
+	AssertFileContent(t, filepath.Join(outDir, "glider.lif"), testGliderLife)
+	AssertFileContent(t, filepath.Join(outDir, "blinker.lif"), "#Life 1.06\n0 0\n1 0\n2 0\n")
+
+	os.WriteFile(filepath.Join(dir, "bad.cells"), []byte("X"), 0644)
+	AssertCliCommand(t, []string{"convert", "-in", dir, "-out", outDir, "-format", "cells"}, CLI_EXIT_ERROR)
+	AssertFileContent(t, filepath.Join(outDir, "blinker.cells"), "!Name: blinker\nOOO\n")
+
+	// Sub directories are converted in to the same tree under -out. outDir is not converted again
+	os.Remove(filepath.Join(dir, "bad.cells"))
+	os.MkdirAll(filepath.Join(dir, "sub", "deeper"), 0755)
+	os.MkdirAll(filepath.Join(dir, ".hidden"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "deeper", "line.lif"), []byte("#Life 1.06\n0 0\n0 1\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".hidden", "skip.lif"), []byte("#Life 1.06\n0 0\n"), 0644)
+	stdout = AssertCliCommand(t, []string{"convert", "-in", dir, "-out", outDir, "-format", "cells"}, CLI_EXIT_OK)
+	if !strings.HasSuffix(stdout, "Converted 3 file(s). 0 failed\n") {
+		t.Errorf("convert tree output is wrong:\n%s", stdout)
+	}
+	AssertFileContent(t, filepath.Join(outDir, "sub", "deeper", "line.cells"), "!Name: line\nO\nO\n")
+	if _, err := os.Stat(filepath.Join(outDir, ".hidden")); !os.IsNotExist(err) {
+		t.Errorf("convert tree: .hidden should not be converted")
+	}
+	// Two inputs that would write the same output file both fail
+	os.WriteFile(filepath.Join(dir, "sub", "deeper", "line.cells"), []byte("!Name: other\nOO\n"), 0644)
+	var sout, serr strings.Builder
+	if RunCommand([]string{"convert", "-in", dir, "-out", outDir}, &sout, &serr) != CLI_EXIT_ERROR || !strings.HasSuffix(sout.String(), "Converted 2 file(s). 2 failed\n") || strings.Count(serr.String(), "would be written by more than one input file") != 2 {
+		t.Errorf("convert duplicate outputs:\n%s\n%s", sout.String(), serr.String())
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "sub", "deeper", "line.rle")); !os.IsNotExist(err) {
+		t.Errorf("convert duplicate outputs: line.rle should not be written")
+	}
+
+	AssertCliCommand(t, []string{"convert", "-in", dir}, CLI_EXIT_USAGE)
+	AssertCliCommand(t, []string{"convert", "-in", filepath.Join(dir, "glider.cells"), "-out", filepath.Join(dir, "glider.mc")}, CLI_EXIT_USAGE)
+	AssertCliCommand(t, []string{"convert", "-in", filepath.Join(dir, "missing.cells"), "-out", outDir}, CLI_EXIT_ERROR)
+}
+
+func AssertPatternRead(t *testing.T, content string, format PatternFormat, expEnc string) *RLE {
+	rle, err := NewPatternReader(strings.NewReader(content), "test", format)
+	if err != nil {
+		t.Fatalf("read %s failed %s", format, err.Error())
+	}
+	if rle.encoded != expEnc {
+		t.Errorf("read %s encoded '%s' expected '%s'", format, rle.encoded, expEnc)
+	}
+	return rle
+}
+
+func AssertPatternReadError(t *testing.T, content string, format PatternFormat, exp string) {
+	_, err := NewPatternReader(strings.NewReader(content), "test", format)
+	if err == nil {
+		t.Errorf("read %s '%s' should fail with '%s'", format, content, exp)
+		return
+	}
+	if err.Error() != exp {
+		t.Errorf("read %s error '%s' expected '%s'", format, err.Error(), exp)
+	}
+}
+
+func AssertFileContent(t *testing.T, fileName, exp string) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Errorf("file %s not readable %s", fileName, err.Error())
+		return
+	}
+	if string(b) != exp {
+		t.Errorf("file %s content:\n%s\nexpected:\n%s", fileName, string(b), exp)
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	INDEX_FILE_NAME        = ".grtest-index.json" // Hidden so the file browser does not show it
+	INDEX_CACHE_DIR        = "grtest"             // In the user cache directory. See PatternIndexCacheFile
+	INDEX_VERSION          = 2                    // Change if the entries change so old indexes are rebuilt
+	INDEX_PERIOD_GENS      = 200                  // Default generations searched for the period
+	INDEX_PERIOD_MAX_CELLS = 500                  // Larger patterns, or patterns that grow larger, have no period
+
+	INDEX_KIND_STILL      = "still"
+	INDEX_KIND_OSCILLATOR = "oscillator"
+	INDEX_KIND_SPACESHIP  = "spaceship"
+	INDEX_KIND_UNKNOWN    = ""
+)
+
+// The metadata for a pattern file.
+// Period is 0 if the pattern did not repeat (or was too large to run).
+type PatternIndexEntry struct {
+	Path       string   `json:"path"` // Relative to the index root, using '/'
+	Name       string   `json:"name"`
+	Owner      string   `json:"owner"`
+	Comments   []string `json:"comments,omitempty"`
+	Rule       string   `json:"rule"`
+	Width      int64    `json:"width"`
+	Height     int64    `json:"height"`
+	Population int      `json:"population"`
+	Period     int      `json:"period"`
+	Kind       string   `json:"kind"`
+	Hash       string   `json:"hash"`    // See CanonicalHash. The same object in any position or orientation has the same hash
+	ModTime    int64    `json:"modTime"` // File modification time in unix nano seconds
+	Size       int64    `json:"size"`    // File size in bytes
+	Error      string   `json:"error,omitempty"`
+}
+
+// An index of all of the pattern files in a directory tree. Saved as JSON in the root directory.
+type PatternIndex struct {
+	Version    int                           `json:"version"`
+	PeriodGens int                           `json:"periodGens"`
+	Entries    map[string]*PatternIndexEntry `json:"entries"`
+	root       string
+	fileName   string
+}
+
+// Counts returned by Update
+type PatternIndexChanges struct {
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+}
+
+// Create an empty index for the directory tree at root.
+// If fileName is empty the index is kept in root/.grtest-index.json.
+func NewPatternIndex(root, fileName string) *PatternIndex {
+	if fileName == "" {
+		fileName = filepath.Join(root, INDEX_FILE_NAME)
+	}
+	return &PatternIndex{Version: INDEX_VERSION, PeriodGens: INDEX_PERIOD_GENS, Entries: make(map[string]*PatternIndexEntry), root: root, fileName: fileName}
+}
+
+// Load the index for root. If the index file does not exist or is an old version an empty index is returned.
+func LoadPatternIndex(root, fileName string) (*PatternIndex, error) {
+	pi := NewPatternIndex(root, fileName)
+	b, err := os.ReadFile(pi.fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pi, nil
+		}
+		return nil, err
+	}
+	loaded := NewPatternIndex(root, fileName)
+	if err := json.Unmarshal(b, loaded); err != nil {
+		return nil, fmt.Errorf("index file '%s' is invalid. %s", pi.fileName, err.Error())
+	}
+	if loaded.Version != INDEX_VERSION || loaded.Entries == nil {
+		return pi, nil
+	}
+	return loaded, nil
+}
+
+// The index file for root in the user cache directory. The GUI uses this so it does not write to the directories being browsed
+func PatternIndexCacheFile(root string) string {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		abs = root
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	sum := sha1.Sum([]byte(abs))
+	return filepath.Join(dir, INDEX_CACHE_DIR, fmt.Sprintf("index-%x.json", sum[:8]))
+}
+
+// Save the index. The directory is created if required
+func (pi *PatternIndex) Save() error {
+	b, err := json.MarshalIndent(pi, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(pi.fileName), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(pi.fileName, b, 0644)
+}
+
+func (pi *PatternIndex) FileName() string {
+	return pi.fileName
+}
+
+func (pi *PatternIndex) Root() string {
+	return pi.root
+}
+
+// Scan the directory tree. Files that are new or have changed (size or modification time) are read.
+// Entries for files that no longer exist are removed.
+// Directories and files starting with '.' or '_' are skipped as in the file browser.
+func (pi *PatternIndex) Update() (*PatternIndexChanges, error) {
+	changes := &PatternIndexChanges{}
+	found := make(map[string]bool)
+	err := filepath.WalkDir(pi.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if p != pi.root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !IsPatternFile(name) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(pi.root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		found[rel] = true
+		old := pi.Entries[rel]
+		if old != nil && old.ModTime == info.ModTime().UnixNano() && old.Size == info.Size() {
+			changes.Unchanged++
+			return nil
+		}
+		if old == nil {
+			changes.Added++
+		} else {
+			changes.Updated++
+		}
+		pi.Entries[rel] = NewPatternIndexEntry(p, rel, info, pi.PeriodGens)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for rel := range pi.Entries {
+		if !found[rel] {
+			delete(pi.Entries, rel)
+			changes.Removed++
+		}
+	}
+	return changes, nil
+}
+
+// Read a pattern file and extract its metadata. Load errors are recorded in the entry.
+func NewPatternIndexEntry(fileName, rel string, info fs.FileInfo, periodGens int) *PatternIndexEntry {
+	e := &PatternIndexEntry{Path: rel, ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+	rle, err := LoadPattern(fileName)
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	e.Name = rle.name
+	e.Owner = rle.owner
+	e.Comments = rle.comments
+	e.Rule = rle.rule
+	if r, err := ParseLifeRule(rle.rule); err == nil {
+		e.Rule = r.String()
+	}
+	e.Population = len(rle.coords) / 2
+	e.Hash = CanonicalHashString(rle.Hash())
+	if e.Population > 0 {
+		_, e.Width, e.Height = POCNormaliseCoords(rle.coords)
+	}
+	if e.Population > 0 && e.Population <= INDEX_PERIOD_MAX_CELLS && e.Rule == LIFE_RULE_CONWAY {
+		period, dx, dy, _ := apgClassifyLimit(rle.coords, periodGens, INDEX_PERIOD_MAX_CELLS)
+		e.Period = period
+		switch {
+		case period == 0:
+			e.Kind = INDEX_KIND_UNKNOWN
+		case dx != 0 || dy != 0:
+			e.Kind = INDEX_KIND_SPACESHIP
+		case period == 1:
+			e.Kind = INDEX_KIND_STILL
+		default:
+			e.Kind = INDEX_KIND_OSCILLATOR
+		}
+	}
+	return e
+}
+
+// The full path of the file for an entry
+func (pi *PatternIndex) FullPath(e *PatternIndexEntry) string {
+	return filepath.Join(pi.root, filepath.FromSlash(e.Path))
+}
+
+// Find the entry for a file if it is in the index and has not changed.
+func (pi *PatternIndex) Lookup(fileName string) *PatternIndexEntry {
+	rel, err := filepath.Rel(pi.root, fileName)
+	if err != nil {
+		return nil
+	}
+	e := pi.Entries[filepath.ToSlash(rel)]
+	if e == nil {
+		return nil
+	}
+	info, err := os.Stat(fileName)
+	if err != nil || info.ModTime().UnixNano() != e.ModTime || info.Size() != e.Size {
+		return nil
+	}
+	return e
+}
+
+// Return the entries that match the query sorted by path
+func (pi *PatternIndex) Search(q *PatternQuery) []*PatternIndexEntry {
+	resp := make([]*PatternIndexEntry, 0)
+	for _, e := range pi.Entries {
+		if q.Match(e) {
+			resp = append(resp, e)
+		}
+	}
+	sort.Slice(resp, func(i, j int) bool { return resp[i].Path < resp[j].Path })
+	return resp
+}
+
+// Groups of entries with the same canonical hash, so hold the same object. Each group and the groups are sorted by path
+func (pi *PatternIndex) Duplicates() [][]*PatternIndexEntry {
+	byHash := make(map[string][]*PatternIndexEntry)
+	for _, e := range pi.Entries {
+		if e.Error == "" {
+			byHash[e.Hash] = append(byHash[e.Hash], e)
+		}
+	}
+	resp := make([][]*PatternIndexEntry, 0)
+	for _, group := range byHash {
+		if len(group) > 1 {
+			sort.Slice(group, func(i, j int) bool { return group[i].Path < group[j].Path })
+			resp = append(resp, group)
+		}
+	}
+	sort.Slice(resp, func(i, j int) bool { return resp[i][0].Path < resp[j][0].Path })
+	return resp
+}
+
+// A search of the index. Zero values are not checked.
+// Words must all be found (ignoring case) in the path, name, owner or comments.
+type PatternQuery struct {
+	Words  []string
+	Rule   string
+	Kind   string
+	Period int
+	Hash   string
+	ranges map[string][2]int64 // name -> min, max. See queryRangeNames
+}
+
+var queryRangeNames = []string{"w", "h", "size", "pop"}
+
+/*
+Parse a search. Words are separated by spaces. For example:
+
+	glider rule:B3/S23 kind:spaceship period:4 w<=10 h>3 size<20 pop=5 hash:0e56fd0119a3f7c4
+
+size is the larger of the width and height.
+*/
+func ParsePatternQuery(query string) (*PatternQuery, error) {
+	q := &PatternQuery{Words: make([]string, 0), ranges: make(map[string][2]int64)}
+	for _, word := range strings.Fields(query) {
+		lw := strings.ToLower(word)
+		switch {
+		case strings.HasPrefix(lw, "rule:"):
+			r, err := ParseLifeRule(word[5:])
+			if err != nil {
+				return nil, err
+			}
+			q.Rule = r.String()
+		case strings.HasPrefix(lw, "kind:"):
+			q.Kind = lw[5:]
+			if q.Kind != INDEX_KIND_STILL && q.Kind != INDEX_KIND_OSCILLATOR && q.Kind != INDEX_KIND_SPACESHIP {
+				return nil, fmt.Errorf("kind '%s' must be %s, %s or %s", word[5:], INDEX_KIND_STILL, INDEX_KIND_OSCILLATOR, INDEX_KIND_SPACESHIP)
+			}
+		case strings.HasPrefix(lw, "hash:"):
+			q.Hash = lw[5:]
+		case strings.HasPrefix(lw, "period:"):
+			n, err := strconv.Atoi(lw[7:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("period '%s' must be a number greater than 0", word[7:])
+			}
+			q.Period = n
+		default:
+			ok, err := q.parseRange(lw)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				q.Words = append(q.Words, lw)
+			}
+		}
+	}
+	return q, nil
+}
+
+// Parse name<n name<=n name>n name>=n or name=n. Returns false if the word is not a range.
+func (q *PatternQuery) parseRange(word string) (bool, error) {
+	for _, name := range queryRangeNames {
+		if !strings.HasPrefix(word, name) {
+			continue
+		}
+		rest := word[len(name):]
+		op := rest[:len(rest)-len(strings.TrimLeft(rest, "<>="))]
+		if op != "<" && op != "<=" && op != ">" && op != ">=" && op != "=" {
+			continue
+		}
+		n, err := strconv.ParseInt(rest[len(op):], 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("'%s' must be %s%s followed by a number", word, name, op)
+		}
+		r, ok := q.ranges[name]
+		if !ok {
+			r = [2]int64{0, 1<<63 - 1}
+		}
+		switch op {
+		case "<":
+			r[1] = n - 1
+		case "<=":
+			r[1] = n
+		case ">":
+			r[0] = n + 1
+		case ">=":
+			r[0] = n
+		case "=":
+			r[0], r[1] = n, n
+		}
+		q.ranges[name] = r
+		return true, nil
+	}
+	return false, nil
+}
+
+func (q *PatternQuery) Match(e *PatternIndexEntry) bool {
+	if q.Rule != "" && q.Rule != e.Rule {
+		return false
+	}
+	if q.Kind != "" && q.Kind != e.Kind {
+		return false
+	}
+	if q.Period != 0 && q.Period != e.Period {
+		return false
+	}
+	if q.Hash != "" && q.Hash != e.Hash {
+		return false
+	}
+	values := map[string]int64{"w": e.Width, "h": e.Height, "size": maxInt64(e.Width, e.Height), "pop": int64(e.Population)}
+	for name, r := range q.ranges {
+		if values[name] < r[0] || values[name] > r[1] {
+			return false
+		}
+	}
+	if len(q.Words) == 0 {
+		return true
+	}
+	text := strings.ToLower(strings.Join(append([]string{e.Path, e.Name, e.Owner}, e.Comments...), "\n"))
+	for _, w := range q.Words {
+		if !strings.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// A single line description of the entry
+func (e *PatternIndexEntry) String() string {
+	if e.Error != "" {
+		return fmt.Sprintf("%s | ERROR %s", e.Path, e.Error)
+	}
+	kind := e.Kind
+	if kind == INDEX_KIND_UNKNOWN {
+		kind = "-"
+	}
+	return fmt.Sprintf("%s | %s | %s %dx%d pop:%d period:%d %s", e.Path, e.Name, e.Rule, e.Width, e.Height, e.Population, e.Period, kind)
+}
+
+// The first comment or an empty string
+func (e *PatternIndexEntry) Comment() string {
+	if len(e.Comments) == 0 {
+		return ""
+	}
+	return e.Comments[0]
+}
+
+// The modification time as a time
+func (e *PatternIndexEntry) Modified() time.Time {
+	return time.Unix(0, e.ModTime)
+}
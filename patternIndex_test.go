@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPatternIndexUpdate(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "osc"), 0755)
+	os.MkdirAll(filepath.Join(dir, "_skip"), 0755)
+	os.WriteFile(filepath.Join(dir, "glider.cells"), []byte(testGliderCells), 0644)
+	os.WriteFile(filepath.Join(dir, "osc", "blinker.rle"), []byte("#N Blinker\n#C Period 2\n#C Found by Conway\nx = 3, y = 1, rule = B3/S23\n3o!"), 0644)
+	os.WriteFile(filepath.Join(dir, "block.lif"), []byte("#Life 1.06\n0 0\n1 0\n0 1\n1 1\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "bad.rle"), []byte("x = 3, y = 1\n3q!"), 0644)
+	os.WriteFile(filepath.Join(dir, "_skip", "hidden.rle"), []byte("3o!"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("3o!"), 0644)
+
+	pi, err := LoadPatternIndex(dir, "")
+	if err != nil {
+		t.Fatalf("load of a missing index failed %s", err.Error())
+	}
+	AssertIndexChanges(t, pi, "4 0 0 0")
+	if err := pi.Save(); err != nil {
+		t.Fatalf("save failed %s", err.Error())
+	}
+
+	blinker := pi.Entries["osc/blinker.rle"]
+	if blinker == nil || blinker.Name != "Blinker" || strings.Join(blinker.Comments, "|") != "Period 2|Found by Conway" || blinker.Rule != "B3/S23" {
+		t.Fatalf("blinker entry is wrong %+v", blinker)
+	}
+	if blinker.Width != 3 || blinker.Height != 1 || blinker.Population != 3 || blinker.Period != 2 || blinker.Kind != INDEX_KIND_OSCILLATOR {
+		t.Errorf("blinker size or period is wrong %+v", blinker)
+	}
+	glider := pi.Entries["glider.cells"]
+	if glider.Owner != "Richard K. Guy" || glider.Period != 4 || glider.Kind != INDEX_KIND_SPACESHIP || glider.Comment() != "The smallest spaceship" {
+		t.Errorf("glider entry is wrong %+v", glider)
+	}
+	if pi.Entries["block.lif"].Kind != INDEX_KIND_STILL || !strings.Contains(pi.Entries["bad.rle"].Error, "unexpected character 'q'") {
+		t.Errorf("block or bad entry is wrong %+v %+v", pi.Entries["block.lif"], pi.Entries["bad.rle"])
+	}
+
+	// Reload. Change one file, add one and remove one
+	pi, _ = LoadPatternIndex(dir, "")
+	if len(pi.Entries) != 4 {
+		t.Fatalf("reloaded index has %d entries expected 4", len(pi.Entries))
+	}
+	os.WriteFile(filepath.Join(dir, "bad.rle"), []byte("x = 2, y = 1\n2o!"), 0644)
+	os.Chtimes(filepath.Join(dir, "bad.rle"), time.Now(), time.Now().Add(time.Hour))
+	os.WriteFile(filepath.Join(dir, "osc", "toad.rle"), []byte("x = 4, y = 2\nb3o$3o!"), 0644)
+	os.Remove(filepath.Join(dir, "block.lif"))
+	AssertIndexChanges(t, pi, "1 1 1 2")
+	if pi.Entries["bad.rle"].Error != "" || pi.Entries["bad.rle"].Population != 2 {
+		t.Errorf("changed file was not indexed again %+v", pi.Entries["bad.rle"])
+	}
+	// The blinker in a different file is found as a duplicate
+	os.WriteFile(filepath.Join(dir, "vblinker.lif"), []byte("#Life 1.06\n5 5\n5 6\n5 7\n"), 0644)
+	AssertIndexChanges(t, pi, "1 0 0 4")
+	dups := pi.Duplicates()
+	if len(dups) != 1 || len(dups[0]) != 2 || dups[0][0].Path != "osc/blinker.rle" || dups[0][1].Path != "vblinker.lif" {
+		t.Errorf("duplicates are wrong %v", dups)
+	}
+	AssertIndexSearch(t, pi, "hash:"+blinker.Hash, "osc/blinker.rle vblinker.lif")
+	if pi.Lookup(filepath.Join(dir, "osc", "toad.rle")) == nil || pi.Lookup(filepath.Join(dir, "block.lif")) != nil {
+		t.Errorf("lookup returned the wrong entries")
+	}
+}
+
+func TestPatternIndexCacheFile(t *testing.T) {
+	dir := t.TempDir()
+	a, b := PatternIndexCacheFile(dir), PatternIndexCacheFile(filepath.Join(dir, "sub"))
+	if a == b || strings.HasPrefix(a, dir) || filepath.Base(filepath.Dir(a)) != INDEX_CACHE_DIR {
+		t.Errorf("cache files are wrong '%s' '%s'", a, b)
+	}
+	if a != PatternIndexCacheFile(filepath.Join(dir, "sub", "..")) {
+		t.Errorf("cache file should be the same for the same directory")
+	}
+	// The directory for the index is created when it is saved. Nothing is written to the indexed directory
+	os.WriteFile(filepath.Join(dir, "blinker.rle"), []byte("x = 3, y = 1\n3o!"), 0644)
+	fn := filepath.Join(t.TempDir(), "cache", "index.json")
+	pi := NewPatternIndex(dir, fn)
+	AssertIndexChanges(t, pi, "1 0 0 0")
+	if err := pi.Save(); err != nil {
+		t.Fatalf("save failed %s", err.Error())
+	}
+	if _, err := os.Stat(filepath.Join(dir, INDEX_FILE_NAME)); err == nil {
+		t.Errorf("index should not be written to the indexed directory")
+	}
+	if pi, _ = LoadPatternIndex(dir, fn); len(pi.Entries) != 1 {
+		t.Errorf("saved index has %d entries expected 1", len(pi.Entries))
+	}
+}
+
+func TestPatternIndexSearch(t *testing.T) {
+	pi := NewPatternIndex("", "")
+	pi.Entries["a/glider.rle"] = &PatternIndexEntry{Path: "a/glider.rle", Name: "Glider", Rule: "B3/S23", Width: 3, Height: 3, Population: 5, Period: 4, Kind: INDEX_KIND_SPACESHIP}
+	pi.Entries["blinker.rle"] = &PatternIndexEntry{Path: "blinker.rle", Name: "Blinker", Comments: []string{"Found by Conway"}, Rule: "B3/S23", Width: 3, Height: 1, Population: 3, Period: 2, Kind: INDEX_KIND_OSCILLATOR}
+	pi.Entries["b/seeds.rle"] = &PatternIndexEntry{Path: "b/seeds.rle", Name: "Seeds", Rule: "B2/S", Width: 20, Height: 8, Population: 40}
+
+	AssertIndexSearch(t, pi, "", "a/glider.rle b/seeds.rle blinker.rle")
+	AssertIndexSearch(t, pi, "conway", "blinker.rle")
+	AssertIndexSearch(t, pi, "GLIDER a/", "a/glider.rle")
+	AssertIndexSearch(t, pi, "rule:b3/s23", "a/glider.rle blinker.rle")
+	AssertIndexSearch(t, pi, "rule:/2", "b/seeds.rle")
+	AssertIndexSearch(t, pi, "kind:spaceship", "a/glider.rle")
+	AssertIndexSearch(t, pi, "period:2", "blinker.rle")
+	AssertIndexSearch(t, pi, "size<4", "a/glider.rle blinker.rle")
+	AssertIndexSearch(t, pi, "w>=3 h<=1", "blinker.rle")
+	AssertIndexSearch(t, pi, "pop>5", "b/seeds.rle")
+	AssertIndexSearch(t, pi, "pop=5", "a/glider.rle")
+	AssertIndexSearch(t, pi, "size>3 size<10", "")
+
+	for query, exp := range map[string]string{
+		"kind:puffer": "kind 'puffer' must be still, oscillator or spaceship",
+		"period:x":    "period 'x' must be a number greater than 0",
+		"w<a":         "'w<a' must be w< followed by a number",
+		"rule:B3":     "rule 'B3' must be in the form B3/S23",
+	} {
+		_, err := ParsePatternQuery(query)
+		if err == nil || err.Error() != exp {
+			t.Errorf("query '%s' error %v expected '%s'", query, err, exp)
+		}
+	}
+}
+
+func TestCliIndexSearch(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "glider.cells"), []byte(testGliderCells), 0644)
+	os.WriteFile(filepath.Join(dir, "blinker.rle"), []byte("#N Blinker\nx = 3, y = 1\n3o!"), 0644)
+	out := AssertCliCommand(t, []string{"index", "-dir", dir}, CLI_EXIT_OK)
+	if !strings.Contains(out, "2 added, 0 updated, 0 removed, 0 unchanged") {
+		t.Errorf("index output is wrong:\n%s", out)
+	}
+	out = AssertCliCommand(t, []string{"search", "-dir", dir, "kind:spaceship"}, CLI_EXIT_OK)
+	if out != "glider.cells | Glider | B3/S23 3x3 pop:5 period:4 spaceship\nFound 1 of 2 pattern(s)\n" {
+		t.Errorf("search output is wrong:\n%s", out)
+	}
+	AssertCliCommand(t, []string{"search", "-dir", dir, "kind:x"}, CLI_EXIT_USAGE)
+	AssertCliCommand(t, []string{"index"}, CLI_EXIT_USAGE)
+}
+
+// exp is 'added updated removed unchanged'
+func AssertIndexChanges(t *testing.T, pi *PatternIndex, exp string) {
+	c, err := pi.Update()
+	if err != nil {
+		t.Fatalf("update failed %s", err.Error())
+	}
+	got := fmt.Sprintf("%d %d %d %d", c.Added, c.Updated, c.Removed, c.Unchanged)
+	if got != exp {
+		t.Errorf("index changes '%s' expected '%s'", got, exp)
+	}
+}
+
+func AssertIndexSearch(t *testing.T, pi *PatternIndex, query, exp string) {
+	q, err := ParsePatternQuery(query)
+	if err != nil {
+		t.Errorf("query '%s' failed %s", query, err.Error())
+		return
+	}
+	paths := make([]string, 0)
+	for _, e := range pi.Search(q) {
+		paths = append(paths, e.Path)
+	}
+	if strings.Join(paths, " ") != exp {
+		t.Errorf("query '%s' found '%s' expected '%s'", query, strings.Join(paths, " "), exp)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	GRAPH_POPULATION = 0
+	GRAPH_BIRTHS     = 1
+	GRAPH_DEATHS     = 2
+	GRAPH_AREA       = 3 // Area of the bounding box of the cells
+	GRAPH_SERIES     = 4
+
+	GRAPH_HISTORY = 2000 // Samples kept. Older samples are dropped
+	GRAPH_POINTS  = 250  // Most points drawn for each series. Samples are skipped to fit
+	GRAPH_HEIGHT  = 120
+	GRAPH_PAD     = 4
+)
+
+var (
+	GraphSeriesNames = []string{"Population", "Births", "Deaths", "Area"}
+	graphColours     = []color.Color{FC_CELL, color.RGBA{0, 200, 0, 255}, color.RGBA{255, 80, 80, 255}, color.RGBA{255, 200, 0, 255}}
+)
+
+// The numbers for one generation
+type PopulationSample struct {
+	Gen        int
+	Population int
+	Births     int
+	Deaths     int
+	Area       int64
+}
+
+func (s PopulationSample) Value(series int) int64 {
+	switch series {
+	case GRAPH_BIRTHS:
+		return int64(s.Births)
+	case GRAPH_DEATHS:
+		return int64(s.Deaths)
+	case GRAPH_AREA:
+		return s.Area
+	}
+	return int64(s.Population)
+}
+
+func (s PopulationSample) String() string {
+	return fmt.Sprintf("Gen:%d Pop:%d Births:%d Deaths:%d Area:%d", s.Gen, s.Population, s.Births, s.Deaths, s.Area)
+}
+
+// The last max samples in generation order. A sample for an earlier generation (after a reset or undo) clears the history.
+type PopulationHistory struct {
+	samples []PopulationSample
+	max     int
+}
+
+func NewPopulationHistory(max int) *PopulationHistory {
+	return &PopulationHistory{samples: make([]PopulationSample, 0), max: max}
+}
+
+// Add a sample. A sample for the same generation as the last one replaces it, so edits made while stopped are shown.
+// Returns false if nothing changed.
+func (h *PopulationHistory) Add(s PopulationSample) bool {
+	if n := len(h.samples); n > 0 {
+		if s.Gen == h.samples[n-1].Gen {
+			if s == h.samples[n-1] {
+				return false
+			}
+			h.samples[n-1] = s
+			return true
+		}
+		if s.Gen < h.samples[n-1].Gen {
+			h.samples = h.samples[:0]
+		}
+	}
+	h.samples = append(h.samples, s)
+	if len(h.samples) > h.max {
+		h.samples = append(h.samples[:0], h.samples[len(h.samples)-h.max:]...)
+	}
+	return true
+}
+
+func (h *PopulationHistory) Samples() []PopulationSample {
+	return h.samples
+}
+
+/*
+The smallest 1, 2 or 5 times a power of 10 that is not less than v. Used for the top of the y axis so the scale is easy to read.
+*/
+func GraphNiceMax(v int64) int64 {
+	if v <= 1 {
+		return 1
+	}
+	p := int64(math.Pow(10, math.Floor(math.Log10(float64(v)))))
+	for _, m := range []int64{1, 2, 5, 10} {
+		if p*m >= v {
+			return p * m
+		}
+	}
+	return p * 10
+}
+
+/*
+The indexes of the samples to draw when there are n samples and at most max points. The last sample is always drawn.
+*/
+func graphIndexes(n, max int) []int {
+	step := 1
+	if n > max {
+		step = (n + max - 1) / max
+	}
+	resp := make([]int, 0, n/step+1)
+	for i := (n - 1) % step; i < n; i = i + step {
+		resp = append(resp, i)
+	}
+	return resp
+}
+
+/*
+A graph of the PopulationHistory drawn with lines. The y axis is scaled to the largest value shown.
+Moving the mouse over the graph shows the numbers for the nearest generation.
+*/
+type PopulationGraph struct {
+	widget.BaseWidget
+	mu       sync.Mutex
+	history  *PopulationHistory
+	show     [GRAPH_SERIES]bool
+	hoverX   float32
+	hovering bool
+}
+
+var _ desktop.Hoverable = (*PopulationGraph)(nil)
+
+func NewPopulationGraph() *PopulationGraph {
+	g := &PopulationGraph{history: NewPopulationHistory(GRAPH_HISTORY)}
+	g.show[GRAPH_POPULATION] = true
+	g.ExtendBaseWidget(g)
+	return g
+}
+
+// Add a sample. The graph is not refreshed
+func (g *PopulationGraph) Add(s PopulationSample) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.history.Add(s)
+}
+
+// Show or hide a series. See GRAPH_POPULATION etc
+func (g *PopulationGraph) SetShow(series int, show bool) {
+	g.mu.Lock()
+	g.show[series] = show
+	g.mu.Unlock()
+	g.Refresh()
+}
+
+func (g *PopulationGraph) Showing(series int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.show[series]
+}
+
+func (g *PopulationGraph) MouseIn(me *desktop.MouseEvent) {
+	g.MouseMoved(me)
+}
+
+func (g *PopulationGraph) MouseMoved(me *desktop.MouseEvent) {
+	g.mu.Lock()
+	g.hoverX = me.Position.X
+	g.hovering = true
+	g.mu.Unlock()
+	g.Refresh()
+}
+
+func (g *PopulationGraph) MouseOut() {
+	g.mu.Lock()
+	g.hovering = false
+	g.mu.Unlock()
+	g.Refresh()
+}
+
+func (g *PopulationGraph) CreateRenderer() fyne.WidgetRenderer {
+	r := &populationGraphRenderer{
+		graph:     g,
+		bg:        canvas.NewRectangle(color.RGBA{0, 0, 0, 255}),
+		xAxis:     canvas.NewLine(color.Gray{128}),
+		yAxis:     canvas.NewLine(color.Gray{128}),
+		yMaxText:  canvas.NewText("", color.Gray{200}),
+		genText:   canvas.NewText("", color.Gray{200}),
+		hoverLine: canvas.NewLine(color.Gray{200}),
+		hoverText: canvas.NewText("", color.White),
+	}
+	r.yMaxText.TextSize = 11
+	r.genText.TextSize = 11
+	r.hoverText.TextSize = 11
+	r.objects = []fyne.CanvasObject{r.bg, r.xAxis, r.yAxis}
+	for s := 0; s < GRAPH_SERIES; s++ {
+		r.lines[s] = make([]*canvas.Line, GRAPH_POINTS)
+		for i := range r.lines[s] {
+			l := canvas.NewLine(graphColours[s])
+			l.Hide()
+			r.lines[s][i] = l
+			r.objects = append(r.objects, l)
+		}
+	}
+	r.objects = append(r.objects, r.yMaxText, r.genText, r.hoverLine, r.hoverText)
+	return r
+}
+
+type populationGraphRenderer struct {
+	graph     *PopulationGraph
+	bg        *canvas.Rectangle
+	xAxis     *canvas.Line
+	yAxis     *canvas.Line
+	yMaxText  *canvas.Text
+	genText   *canvas.Text
+	hoverLine *canvas.Line
+	hoverText *canvas.Text
+	lines     [GRAPH_SERIES][]*canvas.Line
+	objects   []fyne.CanvasObject
+}
+
+func (r *populationGraphRenderer) Layout(size fyne.Size) {
+	r.bg.Resize(size)
+	r.update(size)
+}
+
+func (r *populationGraphRenderer) MinSize() fyne.Size {
+	return fyne.Size{Width: 100, Height: GRAPH_HEIGHT}
+}
+
+func (r *populationGraphRenderer) Refresh() {
+	r.update(r.graph.Size())
+	canvas.Refresh(r.graph)
+}
+
+func (r *populationGraphRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *populationGraphRenderer) Destroy() {}
+
+// Move the lines to fit the samples in to size
+func (r *populationGraphRenderer) update(size fyne.Size) {
+	g := r.graph
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	samples := g.history.Samples()
+	x1, y1 := float32(GRAPH_PAD), float32(GRAPH_PAD)
+	x2, y2 := size.Width-GRAPH_PAD, size.Height-GRAPH_PAD
+	r.xAxis.Position1, r.xAxis.Position2 = fyne.Position{X: x1, Y: y2}, fyne.Position{X: x2, Y: y2}
+	r.yAxis.Position1, r.yAxis.Position2 = fyne.Position{X: x1, Y: y1}, fyne.Position{X: x1, Y: y2}
+
+	idx := graphIndexes(len(samples), GRAPH_POINTS)
+	var top int64 = 0
+	for s := 0; s < GRAPH_SERIES; s++ {
+		if g.show[s] {
+			for _, i := range idx {
+				top = maxInt64(top, samples[i].Value(s))
+			}
+		}
+	}
+	top = GraphNiceMax(top)
+	// x is the position in idx so the points are evenly spaced
+	dx := (x2 - x1) / float32(maxInt64(1, int64(len(idx)-1)))
+	pos := func(n int, v int64) fyne.Position {
+		return fyne.Position{X: x1 + float32(n)*dx, Y: y2 - float32(v)*(y2-y1)/float32(top)}
+	}
+	for s := 0; s < GRAPH_SERIES; s++ {
+		for n, l := range r.lines[s] {
+			if !g.show[s] || n+1 >= len(idx) {
+				l.Hide()
+				continue
+			}
+			l.Position1 = pos(n, samples[idx[n]].Value(s))
+			l.Position2 = pos(n+1, samples[idx[n+1]].Value(s))
+			l.Show()
+		}
+	}
+
+	r.yMaxText.Text = fmt.Sprintf("%d", top)
+	r.yMaxText.Move(fyne.Position{X: x1 + 2, Y: y1})
+	r.genText.Text = ""
+	if len(samples) > 0 {
+		r.genText.Text = fmt.Sprintf("Gen %d to %d", samples[0].Gen, samples[len(samples)-1].Gen)
+	}
+	r.genText.Move(fyne.Position{X: x2 - r.genText.MinSize().Width, Y: y2 - r.genText.MinSize().Height})
+
+	if !g.hovering || len(idx) == 0 {
+		r.hoverLine.Hide()
+		r.hoverText.Hide()
+		return
+	}
+	n := int(math.Round(float64((g.hoverX - x1) / dx)))
+	n = int(math.Max(0, math.Min(float64(len(idx)-1), float64(n))))
+	hx := x1 + float32(n)*dx
+	r.hoverLine.Position1, r.hoverLine.Position2 = fyne.Position{X: hx, Y: y1}, fyne.Position{X: hx, Y: y2}
+	r.hoverText.Text = samples[idx[n]].String()
+	tx := hx + 4
+	if tx+r.hoverText.MinSize().Width > x2 {
+		tx = hx - 4 - r.hoverText.MinSize().Width
+	}
+	r.hoverText.Move(fyne.Position{X: tx, Y: y1 + 14})
+	r.hoverLine.Show()
+	r.hoverText.Show()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPopulationHistory(t *testing.T) {
+	h := NewPopulationHistory(3)
+	for gen := 1; gen <= 4; gen++ {
+		h.Add(PopulationSample{Gen: gen, Population: gen * 10})
+	}
+	if h.Add(PopulationSample{Gen: 4, Population: 40}) {
+		t.Errorf("Add: Expected false for the same sample")
+	}
+	// An edit while stopped replaces the sample for the same generation
+	if !h.Add(PopulationSample{Gen: 4, Population: 45}) {
+		t.Errorf("Add: Expected true for a changed sample")
+	}
+	AssertHistoryGens(t, h, "2 3 4")
+	if s := h.Samples()[2]; s.Population != 45 {
+		t.Errorf("Replaced sample: Expected population 45. Actual %d", s.Population)
+	}
+	// Back to an earlier generation clears the history
+	h.Add(PopulationSample{Gen: 0})
+	AssertHistoryGens(t, h, "0")
+	if s := h.Samples()[0]; s.Value(GRAPH_POPULATION) != 0 {
+		t.Errorf("Value: Expected 0. Actual %d", s.Value(GRAPH_POPULATION))
+	}
+	s := PopulationSample{Gen: 5, Population: 1, Births: 2, Deaths: 3, Area: 4}
+	if s.Value(GRAPH_BIRTHS) != 2 || s.Value(GRAPH_DEATHS) != 3 || s.Value(GRAPH_AREA) != 4 || s.String() != "Gen:5 Pop:1 Births:2 Deaths:3 Area:4" {
+		t.Errorf("Sample values are wrong %s", s.String())
+	}
+}
+
+func TestPopulationGraphScale(t *testing.T) {
+	for v, exp := range map[int64]int64{0: 1, 1: 1, 2: 2, 3: 5, 7: 10, 10: 10, 11: 20, 180: 200, 201: 500, 5001: 10000} {
+		if got := GraphNiceMax(v); got != exp {
+			t.Errorf("GraphNiceMax(%d) returned %d expected %d", v, got, exp)
+		}
+	}
+	for _, tc := range []struct {
+		n, max int
+		exp    string
+	}{{0, 3, "[]"}, {2, 3, "[0 1]"}, {3, 3, "[0 1 2]"}, {10, 3, "[1 5 9]"}, {7, 3, "[0 3 6]"}} {
+		if got := fmt.Sprint(graphIndexes(tc.n, tc.max)); got != tc.exp {
+			t.Errorf("graphIndexes(%d, %d) returned %s expected %s", tc.n, tc.max, got, tc.exp)
+		}
+	}
+}
+
+func TestLifeBirthsAndDeaths(t *testing.T) {
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	lg.AddCellsAtOffset(0, 0, 0, []int64{0, 0, 1, 0, 2, 0, 10, 10})
+	lg.NextGen()
+	if b, d := lg.GetBirthsAndDeaths(); b != 2 || d != 3 {
+		t.Errorf("Blinker and a single cell: Expected 2 births and 3 deaths. Actual %d, %d", b, d)
+	}
+	lg.Reset()
+	if b, d := lg.GetBirthsAndDeaths(); b != 0 || d != 0 {
+		t.Errorf("Reset: Expected 0 births and 0 deaths. Actual %d, %d", b, d)
+	}
+}
+
+func AssertHistoryGens(t *testing.T, h *PopulationHistory, exp string) {
+	got := ""
+	for i, s := range h.Samples() {
+		if i > 0 {
+			got = got + " "
+		}
+		got = got + fmt.Sprintf("%d", s.Gen)
+	}
+	if got != exp {
+		t.Errorf("History generations '%s' expected '%s'", got, exp)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	PREFS_VERSION   = 1
+	PREFS_DIR       = "grtest" // In the user config directory
+	PREFS_FILE_NAME = "life-prefs.json"
+
+	PREFS_GRID_SIZE = 6.0 // Default cell size
+	PREFS_DELAY     = 100 // Default animation delay in milliseconds
+)
+
+// The Life settings kept between sessions. Empty values are not restored. The default is used instead
+type LifePrefs struct {
+	Version      int     `json:"version"`
+	Wd           string  `json:"wd"`       // The directory for loading files
+	SaveDir      string  `json:"saveDir"`  // The directory of the last save or export
+	LastFile     string  `json:"lastFile"` // The last pattern file loaded. Loaded at startup
+	GridSize     float64 `json:"gridSize"`
+	XOffset      float64 `json:"xOffset"`
+	YOffset      float64 `json:"yOffset"`
+	Delay        int64   `json:"delay"`
+	Owner        string  `json:"owner"` // Name of Owner when saving cells
+	Width        float32 `json:"width"` // The window size. 0 for the size given on the command line
+	Height       float32 `json:"height"`
+	ExportScheme string  `json:"exportScheme"` // Colours for image export. See EXPORT_SCHEMES
+	Round        bool    `json:"round"`        // Draw round cells
+	fileName     string
+}
+
+// The default preferences. If fileName is empty they are kept in the user config directory. See LifePrefsFileName
+func NewLifePrefs(fileName string) *LifePrefs {
+	if fileName == "" {
+		fileName = LifePrefsFileName()
+	}
+	return &LifePrefs{Version: PREFS_VERSION, GridSize: PREFS_GRID_SIZE, Delay: PREFS_DELAY, fileName: fileName}
+}
+
+// The default preferences file. In the current directory if there is no user config directory
+func LifePrefsFileName() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return PREFS_FILE_NAME
+	}
+	return filepath.Join(dir, PREFS_DIR, PREFS_FILE_NAME)
+}
+
+// Load the preferences. If the file does not exist or is an old version the defaults are returned.
+// Values that are out of range, and files and directories that no longer exist, are set to their defaults.
+func LoadLifePrefs(fileName string) (*LifePrefs, error) {
+	p := NewLifePrefs(fileName)
+	b, err := os.ReadFile(p.fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p, nil
+		}
+		return nil, err
+	}
+	loaded := NewLifePrefs(fileName)
+	if err := json.Unmarshal(b, loaded); err != nil {
+		return nil, fmt.Errorf("preferences file '%s' is invalid. %s", p.fileName, err.Error())
+	}
+	if loaded.Version != PREFS_VERSION {
+		return p, nil
+	}
+	loaded.check()
+	return loaded, nil
+}
+
+func (p *LifePrefs) check() {
+	if p.GridSize < LIFE_GRID_MIN || p.GridSize > LIFE_GRID_MAX {
+		p.GridSize = PREFS_GRID_SIZE
+	}
+	if p.Delay < LAUNCH_MIN_DELAY || p.Delay > LAUNCH_MAX_DELAY {
+		p.Delay = PREFS_DELAY
+	}
+	if p.Width < LAUNCH_MIN_SIZE || p.Height < LAUNCH_MIN_SIZE {
+		p.Width, p.Height = 0, 0
+	}
+	if FindExportScheme(p.ExportScheme) == nil {
+		p.ExportScheme = ""
+	}
+	if !prefsIsDir(p.Wd) {
+		p.Wd = ""
+	}
+	if !prefsIsDir(p.SaveDir) {
+		p.SaveDir = ""
+	}
+	if fi, err := os.Stat(p.LastFile); err != nil || fi.IsDir() || !IsPatternFile(p.LastFile) {
+		p.LastFile = ""
+	}
+}
+
+func prefsIsDir(dir string) bool {
+	fi, err := os.Stat(dir)
+	return err == nil && fi.IsDir()
+}
+
+// Save the preferences. The directory is created if required
+func (p *LifePrefs) Save() error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.fileName), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p.fileName, b, 0644)
+}
+
+// Set all values to their defaults. The file is not changed until Save is called
+func (p *LifePrefs) Reset() {
+	*p = *NewLifePrefs(p.fileName)
+}
+
+func (p *LifePrefs) FileName() string {
+	return p.fileName
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLifePrefsSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "sub", PREFS_FILE_NAME)
+	p := AssertLoadLifePrefs(t, fn)
+	if p.GridSize != PREFS_GRID_SIZE || p.Delay != PREFS_DELAY || p.Wd != "" || p.Width != 0 || p.FileName() != fn {
+		t.Errorf("Missing file: Expected the defaults. Actual %+v", p)
+	}
+	p.Wd, p.SaveDir, p.LastFile = dir, "testdata", "testdata/blinker.rle"
+	p.GridSize, p.XOffset, p.YOffset, p.Delay = 2.5, -10, 20, 50
+	p.Owner, p.ExportScheme, p.Round = "Stuart", "dark", true
+	p.Width, p.Height = 800, 600
+	if err := p.Save(); err != nil {
+		t.Fatalf("Save failed %s", err.Error())
+	}
+	loaded := AssertLoadLifePrefs(t, fn)
+	if *loaded != *p {
+		t.Errorf("Load: Expected %+v. Actual %+v", p, loaded)
+	}
+	loaded.Reset()
+	if *loaded != *NewLifePrefs(fn) {
+		t.Errorf("Reset: Expected the defaults. Actual %+v", loaded)
+	}
+}
+
+func TestLifePrefsCheck(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, PREFS_FILE_NAME)
+	os.WriteFile(fn, []byte(`{"version":1,"wd":"missing","saveDir":"testdata/blinker.rle","lastFile":"testdata/times.txt","gridSize":500,"delay":5,"owner":"Me","width":100,"height":600,"exportScheme":"pink"}`), 0644)
+	p := AssertLoadLifePrefs(t, fn)
+	if p.Wd != "" || p.SaveDir != "" || p.LastFile != "" || p.GridSize != PREFS_GRID_SIZE || p.Delay != PREFS_DELAY || p.Width != 0 || p.Height != 0 || p.ExportScheme != "" {
+		t.Errorf("Invalid values: Expected the defaults. Actual %+v", p)
+	}
+	if p.Owner != "Me" {
+		t.Errorf("Owner: Expected Me. Actual %s", p.Owner)
+	}
+	// An old version is ignored
+	os.WriteFile(fn, []byte(`{"version":0,"owner":"Me"}`), 0644)
+	if p = AssertLoadLifePrefs(t, fn); p.Owner != "" || p.Version != PREFS_VERSION {
+		t.Errorf("Old version: Expected the defaults. Actual %+v", p)
+	}
+	os.WriteFile(fn, []byte(`{"version":`), 0644)
+	if _, err := LoadLifePrefs(fn); err == nil || err.Error() != "preferences file '"+fn+"' is invalid. unexpected end of JSON input" {
+		t.Errorf("Invalid file: Expected an error. Actual %v", err)
+	}
+}
+
+func AssertLoadLifePrefs(t *testing.T, fileName string) *LifePrefs {
+	p, err := LoadLifePrefs(fileName)
+	if err != nil {
+		t.Fatalf("LoadLifePrefs '%s' failed %s", fileName, err.Error())
+	}
+	return p
+}
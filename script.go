@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	SCRIPT_EXT       = ".script"
+	SCRIPT_MAX_STEPS = 10000000 // Largest n for a single step command
+)
+
+/*
+A script runs a list of commands against a LifeGen. One command per line. Blank lines and lines starting with # are ignored.
+File names are relative to the directory of the script.
+
+	rule B36/S23                  Set the rule. The cells are not changed
+	load file [x y]               Clear the universe and place the pattern with its top left at x,y (default 0,0). Sets the rule from the file (default B3/S23)
+	place x y file [rot90|flipx..] Add a pattern with its top left at x,y. Optionally rotated or flipped (see ParseSymmetry)
+	step n                        Run n generations
+	select x1 y1 x2 y2            Select the cells in a rectangle. 'select all' selects every cell
+	save file                     Save the selected cells (or all cells) in the format given by the file extension
+	clear                         Remove all cells
+	assert population n           Stop with an error if the number of cells is not n
+	assert generation n           Stop with an error if the generation count is not n
+*/
+type LifeScript struct {
+	fileName string
+	dir      string
+	lg       *LifeGen
+	log      io.Writer // A line is written for each command
+	selected bool      // If false all cells are selected
+	x1, y1   int64
+	x2, y2   int64
+}
+
+// An error in a script. Line is 1 based.
+type ScriptError struct {
+	FileName string
+	Line     int
+	Msg      string
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.FileName, e.Line, e.Msg)
+}
+
+type scriptCommand struct {
+	name  string
+	usage string
+	nArgs []int // The allowed numbers of arguments
+	run   func(s *LifeScript, args []string) (string, error)
+}
+
+var scriptCommands = []*scriptCommand{
+	{name: "rule", usage: "rule B3/S23", nArgs: []int{1}, run: scriptRule},
+	{name: "load", usage: "load file [x y]", nArgs: []int{1, 3}, run: scriptLoad},
+	{name: "place", usage: "place x y file [rot90|flipx..]", nArgs: []int{3, 4}, run: scriptPlace},
+	{name: "step", usage: "step n", nArgs: []int{1}, run: scriptStep},
+	{name: "select", usage: "select x1 y1 x2 y2 or select all", nArgs: []int{1, 4}, run: scriptSelect},
+	{name: "save", usage: "save file", nArgs: []int{1}, run: scriptSave},
+	{name: "clear", usage: "clear", nArgs: []int{0}, run: scriptClear},
+	{name: "assert", usage: "assert population|generation n", nArgs: []int{2}, run: scriptAssert},
+}
+
+// Create a script that runs against lg. log can be nil.
+func NewLifeScript(fileName string, lg *LifeGen, log io.Writer) *LifeScript {
+	if log == nil {
+		log = io.Discard
+	}
+	return &LifeScript{fileName: fileName, dir: filepath.Dir(fileName), lg: lg, log: log}
+}
+
+func IsScriptFile(fileName string) bool {
+	return strings.EqualFold(filepath.Ext(fileName), SCRIPT_EXT)
+}
+
+// Run the script file
+func (s *LifeScript) RunFile() error {
+	file, err := os.Open(s.fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return s.Run(file)
+}
+
+// Run each line from the reader. Stops at the first error. The error is a *ScriptError
+func (s *LifeScript) Run(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := s.runLine(line); err != nil {
+			return &ScriptError{FileName: s.fileName, Line: lineNo, Msg: err.Error()}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &ScriptError{FileName: s.fileName, Line: lineNo + 1, Msg: err.Error()}
+	}
+	return nil
+}
+
+func (s *LifeScript) runLine(line string) error {
+	fields := strings.Fields(line)
+	name := strings.ToLower(fields[0])
+	for _, c := range scriptCommands {
+		if c.name != name {
+			continue
+		}
+		for _, n := range c.nArgs {
+			if n == len(fields)-1 {
+				msg, err := c.run(s, fields[1:])
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(s.log, "%-40s %s\n", line, msg)
+				return nil
+			}
+		}
+		return fmt.Errorf("'%s' has the wrong number of arguments. Use '%s'", line, c.usage)
+	}
+	names := make([]string, len(scriptCommands))
+	for i, c := range scriptCommands {
+		names[i] = c.name
+	}
+	return fmt.Errorf("unknown command '%s'. Use one of %s", fields[0], strings.Join(names, ","))
+}
+
+// The name of a file relative to the script
+func (s *LifeScript) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(s.dir, name)
+}
+
+func (s *LifeScript) summary() string {
+	return fmt.Sprintf("gen:%d cells:%d", s.lg.GetGenerationCount(), s.lg.CountCells())
+}
+
+func scriptInt(name, v string) (int64, error) {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s '%s' must be a number", name, v)
+	}
+	return n, nil
+}
+
+func scriptRule(s *LifeScript, args []string) (string, error) {
+	rule, err := ParseLifeRule(args[0])
+	if err != nil {
+		return "", err
+	}
+	s.lg.SetRule(rule)
+	return rule.String(), nil
+}
+
+func scriptLoad(s *LifeScript, args []string) (string, error) {
+	var x, y int64
+	var err error
+	if len(args) == 3 {
+		if x, err = scriptInt("x", args[1]); err != nil {
+			return "", err
+		}
+		if y, err = scriptInt("y", args[2]); err != nil {
+			return "", err
+		}
+	}
+	rle, err := LoadPattern(s.path(args[0]))
+	if err != nil {
+		return "", err
+	}
+	rule, err := ParseLifeRule(rle.rule)
+	if err != nil {
+		return "", err
+	}
+	s.lg.Reset()
+	s.lg.SetRule(rule)
+	s.selected = false
+	s.lg.AddCellsAtOffset(x, y, 0, rle.coords)
+	return s.summary(), nil
+}
+
+func scriptPlace(s *LifeScript, args []string) (string, error) {
+	x, err := scriptInt("x", args[0])
+	if err != nil {
+		return "", err
+	}
+	y, err := scriptInt("y", args[1])
+	if err != nil {
+		return "", err
+	}
+	sym := SYM_NONE
+	if len(args) == 4 {
+		sym, err = ParseSymmetry(args[3])
+		if err != nil {
+			return "", err
+		}
+	}
+	rle, err := LoadPattern(s.path(args[2]))
+	if err != nil {
+		return "", err
+	}
+	coords, _, _ := TransformCoords(rle.coords, sym)
+	s.lg.AddCellsAtOffset(x, y, 0, coords)
+	return s.summary(), nil
+}
+
+func scriptStep(s *LifeScript, args []string) (string, error) {
+	n, err := scriptInt("n", args[0])
+	if err != nil {
+		return "", err
+	}
+	if n < 0 || n > SCRIPT_MAX_STEPS {
+		return "", fmt.Errorf("n %d must be between 0 and %d", n, SCRIPT_MAX_STEPS)
+	}
+	s.lg.Step(int(n))
+	return s.summary(), nil
+}
+
+func scriptSelect(s *LifeScript, args []string) (string, error) {
+	if len(args) == 1 {
+		if strings.ToLower(args[0]) != "all" {
+			return "", fmt.Errorf("select '%s' must be 'all' or x1 y1 x2 y2", args[0])
+		}
+		s.selected = false
+		return "all", nil
+	}
+	v := make([]int64, 4)
+	for i, name := range []string{"x1", "y1", "x2", "y2"} {
+		n, err := scriptInt(name, args[i])
+		if err != nil {
+			return "", err
+		}
+		v[i] = n
+	}
+	if v[2] < v[0] || v[3] < v[1] {
+		return "", fmt.Errorf("x2,y2 %d,%d must not be less than x1,y1 %d,%d", v[2], v[3], v[0], v[1])
+	}
+	s.selected = true
+	s.x1, s.y1, s.x2, s.y2 = v[0], v[1], v[2], v[3]
+	return fmt.Sprintf("cells:%d", len(s.SelectedCells())/2), nil
+}
+
+// The x,y pairs of the selected cells. All cells if there is no selection
+func (s *LifeScript) SelectedCells() []int64 {
+	if !s.selected {
+		return s.lg.ListCellsWithMode(0)
+	}
+	resp := make([]int64, 0)
+	s.lg.CellsInBounds(s.x1, s.y1, s.x2, s.y2, func(lc *LifeCell) {
+		resp = append(resp, lc.x, lc.y)
+	})
+	return resp
+}
+
+func scriptSave(s *LifeScript, args []string) (string, error) {
+	fileName := s.path(args[0])
+	format, err := PatternFormatForFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	rle := NewRLESave(fileName, s.SelectedCells(), "", fmt.Sprintf("Generation %d", s.lg.GetGenerationCount()))
+	rle.rule = s.lg.GetRule().String()
+	if err := rle.SaveAs(fileName, format); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("cells:%d", len(rle.coords)/2), nil
+}
+
+func scriptClear(s *LifeScript, args []string) (string, error) {
+	s.lg.Reset()
+	s.selected = false
+	return s.summary(), nil
+}
+
+func scriptAssert(s *LifeScript, args []string) (string, error) {
+	exp, err := scriptInt("n", args[1])
+	if err != nil {
+		return "", err
+	}
+	var actual int64
+	switch strings.ToLower(args[0]) {
+	case "population":
+		actual = int64(s.lg.CountCells())
+	case "generation":
+		actual = int64(s.lg.GetGenerationCount())
+	default:
+		return "", fmt.Errorf("cannot assert '%s'. Use population or generation", args[0])
+	}
+	if actual != exp {
+		return "", fmt.Errorf("assert %s failed. Expected %d actual %d", args[0], exp, actual)
+	}
+	return "ok", nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScriptRun(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "glider.cells"), []byte(testGliderCells), 0644)
+	os.WriteFile(filepath.Join(dir, "blinker.rle"), []byte("x = 3, y = 1\n3o!"), 0644)
+	script := `# Two gliders and a blinker
+load glider.cells
+place 20 0 glider.cells flipx
+place 10 30 blinker.rle rot90
+
+step 4
+assert generation 4
+assert population 13
+select 0 0 5 5
+save out/first.lif
+select all
+save all.lif
+step 1
+assert population 13
+`
+	os.MkdirAll(filepath.Join(dir, "out"), 0755)
+	lg := NewLifeGen(nil, 0)
+	var log strings.Builder
+	s := NewLifeScript(filepath.Join(dir, "test.script"), lg, &log)
+	if err := s.Run(strings.NewReader(script)); err != nil {
+		t.Fatalf("script failed %s\n%s", err.Error(), log.String())
+	}
+	if !strings.Contains(log.String(), "assert population 13") || strings.Count(log.String(), "\n") != 12 {
+		t.Errorf("script log is wrong:\n%s", log.String())
+	}
+	// The first glider moved 1,1 in 4 generations
+	first, err := LoadPattern(filepath.Join(dir, "out", "first.lif"))
+	if err != nil || len(first.coords) != 10 || first.minX != 1 || first.minY != 1 {
+		t.Errorf("selected glider was not saved %v %v", err, first)
+	}
+	all, err := LoadPattern(filepath.Join(dir, "all.lif"))
+	if err != nil || len(all.coords) != 26 {
+		t.Errorf("all cells were not saved %v", err)
+	}
+	if lg.GetGenerationCount() != 5 || lg.IsRunning() {
+		t.Errorf("generation count %d expected 5 and stopped", lg.GetGenerationCount())
+	}
+
+	// The rule is kept by place and clear
+	lg = NewLifeGen(nil, 0)
+	s = NewLifeScript(filepath.Join(dir, "rule.script"), lg, nil)
+	if err := s.Run(strings.NewReader("rule 23/36\nclear\nplace 5 5 blinker.rle\n")); err != nil || lg.GetRule().String() != "B36/S23" {
+		t.Errorf("rule was not kept %v %s", err, lg.GetRule().String())
+	}
+	// load sets the rule from the file. B3/S23 if the file does not have one
+	os.WriteFile(filepath.Join(dir, "replicator.rle"), []byte("x = 5, y = 5, rule = B36/S23\n2b3o$bo2bo$o3bo$o2bo$3o!"), 0644)
+	s = NewLifeScript(filepath.Join(dir, "rule.script"), lg, nil)
+	if err := s.Run(strings.NewReader("load replicator.rle\nstep 12\nassert population 24\n")); err != nil || lg.GetRule().String() != "B36/S23" {
+		t.Errorf("load did not set the rule from the file %v %s", err, lg.GetRule().String())
+	}
+	if err := s.Run(strings.NewReader("load glider.cells 3 3\n")); err != nil || lg.GetRule().String() != "B3/S23" {
+		t.Errorf("load did not set the default rule %v %s", err, lg.GetRule().String())
+	}
+
+	AssertScriptError(t, dir, "step 1\n\nwalk 3", "test.script:3: unknown command 'walk'. Use one of rule,load,place,step,select,save,clear,assert")
+	AssertScriptError(t, dir, "load", "test.script:1: 'load' has the wrong number of arguments. Use 'load file [x y]'")
+	AssertScriptError(t, dir, "place 1 a glider.cells", "test.script:1: y 'a' must be a number")
+	AssertScriptError(t, dir, "load glider.cells 1 a", "test.script:1: y 'a' must be a number")
+	AssertScriptError(t, dir, "place 1 1 glider.cells twist", "test.script:1: unknown rotation or flip 'twist'. Use one of none,rot90,rot180,rot270,flipx,flipy,flipxy,flipyx")
+	AssertScriptError(t, dir, "load glider.cells\nassert population 4", "test.script:2: assert population failed. Expected 4 actual 5")
+	AssertScriptError(t, dir, "assert cells 4", "test.script:1: cannot assert 'cells'. Use population or generation")
+	AssertScriptError(t, dir, "step -1", "test.script:1: n -1 must be between 0 and 10000000")
+	AssertScriptError(t, dir, "select 5 5 1 1", "test.script:1: x2,y2 1,1 must not be less than x1,y1 5,5")
+	AssertScriptError(t, dir, "save out.txt", "out.txt' is not a pattern file. Use one of rle,cells,life106")
+	AssertScriptError(t, dir, "rule B3", "test.script:1: rule 'B3' must be in the form B3/S23")
+}
+
+func TestCliScript(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "blinker.rle"), []byte("x = 3, y = 1\n3o!"), 0644)
+	os.WriteFile(filepath.Join(dir, "ok.script"), []byte("load blinker.rle\nstep 3\nassert population 3\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "fail.script"), []byte("load blinker.rle\nassert population 4\n"), 0644)
+	out := AssertCliCommand(t, []string{"script", "-in", filepath.Join(dir, "ok.script")}, CLI_EXIT_OK)
+	if !strings.Contains(out, "step 3") || !strings.Contains(out, "gen:3 cells:3") {
+		t.Errorf("script output is wrong:\n%s", out)
+	}
+	AssertCliCommand(t, []string{"script", "-q", "-in", filepath.Join(dir, "fail.script")}, CLI_EXIT_ERROR)
+	AssertCliCommand(t, []string{"script"}, CLI_EXIT_USAGE)
+}
+
+func AssertScriptError(t *testing.T, dir, script, exp string) {
+	s := NewLifeScript(filepath.Join(dir, "test.script"), NewLifeGen(nil, 0), nil)
+	err := s.Run(strings.NewReader(script))
+	if err == nil {
+		t.Errorf("script '%s' should fail with '%s'", script, exp)
+		return
+	}
+	if !strings.HasSuffix(err.Error(), exp) {
+		t.Errorf("script error '%s' expected '%s'", err.Error(), exp)
+	}
+}
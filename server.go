@@ -0,0 +1,473 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SERVER_DEFAULT_ADDR = "localhost:8080" // Only accept connections from the same machine
+	SERVER_MAX_STEP     = 100000           // Maximum generations for a single step request
+	SERVER_MAX_BODY     = 10 * 1024 * 1024 // Maximum size of a request body
+)
+
+var serverSessionName = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// A named LifeGen. The mutex must be held while the LifeGen is used.
+type LifeSession struct {
+	mu       sync.Mutex
+	name     string
+	lg       *LifeGen
+	created  time.Time
+	watchers map[*sessionWatcher]bool // Browsers watching the session. See stream.go
+	stop     chan bool                // Not nil while the session is running in the background
+}
+
+// Manages named sessions for the HTTP API. Safe for concurrent clients.
+type LifeServer struct {
+	mu       sync.Mutex
+	sessions map[string]*LifeSession
+	nextId   int
+}
+
+// The JSON description of a session
+type SessionInfo struct {
+	Name       string  `json:"name"`
+	Rule       string  `json:"rule"`
+	Generation int     `json:"generation"`
+	Population int     `json:"population"`
+	Bounds     []int64 `json:"bounds,omitempty"` // x1, y1, x2, y2. Not present if there are no cells
+	Created    string  `json:"created"`
+	GenTimeMs  int64   `json:"genTimeMs"`
+	Running    bool    `json:"running"`
+	Watchers   int     `json:"watchers"`
+}
+
+// The response to the cells request
+type SessionCells struct {
+	*SessionInfo
+	Cells []int64 `json:"cells"` // x,y pairs
+}
+
+type sessionRequest struct {
+	Name  string  `json:"name"`
+	Rule  string  `json:"rule"`
+	Cells []int64 `json:"cells"`
+	N     int     `json:"n"`
+	Clear bool    `json:"clear"` // Remove all cells
+}
+
+type serverError struct {
+	status int
+	msg    string
+}
+
+func (e *serverError) Error() string {
+	return e.msg
+}
+
+func newServerError(status int, format string, args ...interface{}) *serverError {
+	return &serverError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+func NewLifeServer() *LifeServer {
+	return &LifeServer{sessions: make(map[string]*LifeSession), nextId: 1}
+}
+
+/*
+Routes:
+
+	GET    /sessions                  List the sessions
+	POST   /sessions                  Create a session {"name":"a","rule":"B3/S23"}. Name is optional
+	GET    /sessions/{name}           Population, generation and bounds
+	DELETE /sessions/{name}           Remove the session
+	POST   /sessions/{name}/step      Run n generations {"n":10} or ?n=10
+	GET    /sessions/{name}/cells     Cells in a rectangle ?x1=&y1=&x2=&y2=. Default is all cells
+	POST   /sessions/{name}/cells     Add cells {"cells":[x,y,x,y...]}
+	DELETE /sessions/{name}/cells     Remove cells {"cells":[x,y,x,y...]}. {"clear":true} removes all cells
+	GET    /sessions/{name}/rle       The cells as an RLE file
+	PUT    /sessions/{name}/rle       Load RLE text. ?x=&y= offset. ?clear=false to keep the existing cells
+	POST   /sessions/{name}/run       Run in the background ?delay=100 (milliseconds per generation)
+	POST   /sessions/{name}/stop      Stop running in the background
+	GET    /sessions/{name}/watch     Server Sent Events for the viewport ?x1=&y1=&x2=&y2=. See stream.go
+	GET    /viewer                    A browser viewer ?session={name}
+*/
+func (ls *LifeServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sessions", ls.handleSessions)
+	mux.HandleFunc("/sessions/", ls.handleSession)
+	mux.HandleFunc("/viewer", handleViewer)
+	return mux
+}
+
+func (ls *LifeServer) handleSessions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		serverWriteJSON(w, http.StatusOK, ls.List())
+	case http.MethodPost:
+		req := &sessionRequest{}
+		if err := serverReadJSON(r, req); err != nil {
+			serverWriteError(w, err)
+			return
+		}
+		s, err := ls.Create(req.Name, req.Rule)
+		if err != nil {
+			serverWriteError(w, err)
+			return
+		}
+		serverWriteJSON(w, http.StatusCreated, s.Info())
+	default:
+		serverWriteError(w, newServerError(http.StatusMethodNotAllowed, "method %s not allowed for /sessions", r.Method))
+	}
+}
+
+func (ls *LifeServer) handleSession(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/sessions/"), "/")
+	if len(parts) > 2 || parts[0] == "" {
+		serverWriteError(w, newServerError(http.StatusNotFound, "path %s not found", r.URL.Path))
+		return
+	}
+	s := ls.Get(parts[0])
+	if s == nil {
+		serverWriteError(w, newServerError(http.StatusNotFound, "session '%s' not found", parts[0]))
+		return
+	}
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+	var err error
+	switch action + " " + r.Method {
+	case " GET":
+		serverWriteJSON(w, http.StatusOK, s.Info())
+	case " DELETE":
+		ls.Delete(s.name)
+		w.WriteHeader(http.StatusNoContent)
+	case "step POST":
+		err = s.handleStep(w, r)
+	case "cells GET":
+		err = s.handleGetCells(w, r)
+	case "cells POST", "cells DELETE":
+		err = s.handleUpdateCells(w, r)
+	case "rle GET":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, s.RLE())
+	case "rle PUT", "rle POST":
+		err = s.handleLoadRLE(w, r)
+	case "run POST":
+		err = s.handleRun(w, r)
+	case "stop POST":
+		s.Stop()
+		serverWriteJSON(w, http.StatusOK, s.Info())
+	case "watch GET":
+		err = s.handleWatch(w, r)
+	default:
+		err = newServerError(http.StatusNotFound, "%s %s not found", r.Method, r.URL.Path)
+	}
+	if err != nil {
+		serverWriteError(w, err)
+	}
+}
+
+// Create a new session. If name is empty a unique name is generated.
+func (ls *LifeServer) Create(name, rule string) (*LifeSession, error) {
+	if rule == "" {
+		rule = LIFE_RULE_CONWAY
+	}
+	lr, err := ParseLifeRule(rule)
+	if err != nil {
+		return nil, newServerError(http.StatusBadRequest, err.Error())
+	}
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if name == "" {
+		for name == "" || ls.sessions[name] != nil {
+			name = fmt.Sprintf("s%d", ls.nextId)
+			ls.nextId++
+		}
+	}
+	if !serverSessionName.MatchString(name) {
+		return nil, newServerError(http.StatusBadRequest, "session name '%s' must be 1 to 64 letters, digits, '_', '.' or '-'", name)
+	}
+	if ls.sessions[name] != nil {
+		return nil, newServerError(http.StatusConflict, "session '%s' already exists", name)
+	}
+	s := &LifeSession{name: name, lg: NewLifeGen(nil, RUN_FOR_EVER), created: time.Now(), watchers: make(map[*sessionWatcher]bool)}
+	s.lg.SetRule(lr)
+	ls.sessions[name] = s
+	return s, nil
+}
+
+func (ls *LifeServer) Get(name string) *LifeSession {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.sessions[name]
+}
+
+// Remove the session. It is stopped and any watchers are disconnected.
+func (ls *LifeServer) Delete(name string) {
+	ls.mu.Lock()
+	s := ls.sessions[name]
+	delete(ls.sessions, name)
+	ls.mu.Unlock()
+	if s != nil {
+		s.Stop()
+		s.closeWatchers()
+	}
+}
+
+// The info for all sessions sorted by name
+func (ls *LifeServer) List() []*SessionInfo {
+	ls.mu.Lock()
+	list := make([]*LifeSession, 0, len(ls.sessions))
+	for _, s := range ls.sessions {
+		list = append(list, s)
+	}
+	ls.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	resp := make([]*SessionInfo, len(list))
+	for i, s := range list {
+		resp[i] = s.Info()
+	}
+	return resp
+}
+
+func (s *LifeSession) Info() *SessionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.info()
+}
+
+// The mutex must be held
+func (s *LifeSession) info() *SessionInfo {
+	info := &SessionInfo{Name: s.name, Rule: s.lg.GetRule().String(), Generation: s.lg.GetGenerationCount(), Population: s.lg.CountCells(), Created: s.created.Format(time.RFC3339), GenTimeMs: s.lg.GetGenerationTime(), Running: s.stop != nil, Watchers: len(s.watchers)}
+	if s.lg.GetRootCell() != nil {
+		x1, y1, x2, y2 := s.lg.GetBounds()
+		info.Bounds = []int64{x1, y1, x2, y2}
+	}
+	return info
+}
+
+// Run n generations
+func (s *LifeSession) Step(n int) *SessionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lg.SetRunFor(RUN_FOR_EVER, nil)
+	for i := 0; i < n; i++ {
+		s.lg.NextGen()
+	}
+	s.notify()
+	return s.info()
+}
+
+// The info and the cells in the rectangle x1,y1 to x2,y2 inclusive
+func (s *LifeSession) CellsInBounds(x1, y1, x2, y2 int64) *SessionCells {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &SessionCells{SessionInfo: s.info(), Cells: make([]int64, 0)}
+	s.lg.CellsInBounds(x1, y1, x2, y2, func(lc *LifeCell) {
+		resp.Cells = append(resp.Cells, lc.x, lc.y)
+	})
+	return resp
+}
+
+// Add cells at x,y pairs. Returns the number added (duplicates are not added)
+func (s *LifeSession) AddCells(coords []int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.notify()
+	return s.lg.AddCellsAtOffset(0, 0, 0, coords)
+}
+
+// Remove cells at x,y pairs
+func (s *LifeSession) RemoveCells(coords []int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.notify()
+	s.lg.RemoveCells(coords[:len(coords)&^1])
+}
+
+// Remove all cells and reset the generation count
+func (s *LifeSession) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.notify()
+	s.clear()
+}
+
+// Remove all cells and reset the generation count. The mutex must be held
+func (s *LifeSession) clear() {
+	rule := s.lg.GetRule()
+	s.lg.Reset()
+	s.lg.SetRule(rule)
+}
+
+// Load RLE text. The cells are added at x,y. If clear is true the existing cells are removed first.
+func (s *LifeSession) LoadRLE(r io.Reader, x, y int64, clear bool) error {
+	rle, err := NewRleReader(r, s.name)
+	if err != nil {
+		return newServerError(http.StatusBadRequest, err.Error())
+	}
+	if len(rle.coords) > 0 {
+		if err := serverCheckCells([]int64{x, y, x + rle.minX, y + rle.minY, x + rle.maxX, y + rle.maxY}); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if clear {
+		s.clear()
+	}
+	s.lg.AddCellsAtOffset(x, y, 0, rle.coords)
+	s.notify()
+	return nil
+}
+
+// The cells as an RLE file
+func (s *LifeSession) RLE() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rle := NewRLESave(s.name, s.lg.ListCellsWithMode(0), "", fmt.Sprintf("Generation %d", s.lg.GetGenerationCount()))
+	rle.rule = s.lg.GetRule().String()
+	return rle.SaveFileContent()
+}
+
+func (s *LifeSession) handleStep(w http.ResponseWriter, r *http.Request) error {
+	req := &sessionRequest{N: 1}
+	if q := r.URL.Query().Get("n"); q != "" {
+		n, err := strconv.Atoi(q)
+		if err != nil {
+			return newServerError(http.StatusBadRequest, "n '%s' must be a number", q)
+		}
+		req.N = n
+	} else if err := serverReadJSON(r, req); err != nil {
+		return err
+	}
+	if req.N < 0 || req.N > SERVER_MAX_STEP {
+		return newServerError(http.StatusBadRequest, "n %d must be between 0 and %d", req.N, SERVER_MAX_STEP)
+	}
+	serverWriteJSON(w, http.StatusOK, s.Step(req.N))
+	return nil
+}
+
+func (s *LifeSession) handleGetCells(w http.ResponseWriter, r *http.Request) error {
+	rect, err := serverQueryRect(r)
+	if err != nil {
+		return err
+	}
+	serverWriteJSON(w, http.StatusOK, s.CellsInBounds(rect[0], rect[1], rect[2], rect[3]))
+	return nil
+}
+
+func (s *LifeSession) handleUpdateCells(w http.ResponseWriter, r *http.Request) error {
+	req := &sessionRequest{}
+	if err := serverReadJSON(r, req); err != nil {
+		return err
+	}
+	if len(req.Cells)%2 != 0 {
+		return newServerError(http.StatusBadRequest, "cells must be x,y pairs. Found %d values", len(req.Cells))
+	}
+	if err := serverCheckCells(req.Cells); err != nil {
+		return err
+	}
+	switch {
+	case r.Method == http.MethodPost:
+		s.AddCells(req.Cells)
+	case req.Clear && len(req.Cells) > 0:
+		return newServerError(http.StatusBadRequest, "use cells or clear but not both")
+	case req.Clear:
+		s.Clear()
+	case len(req.Cells) == 0:
+		return newServerError(http.StatusBadRequest, "no cells to remove. Use {\"clear\":true} to remove all cells")
+	default:
+		s.RemoveCells(req.Cells)
+	}
+	serverWriteJSON(w, http.StatusOK, s.Info())
+	return nil
+}
+
+func (s *LifeSession) handleLoadRLE(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	ofs := []int64{0, 0}
+	for i, name := range []string{"x", "y"} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return newServerError(http.StatusBadRequest, "%s '%s' must be a number", name, v)
+			}
+			ofs[i] = n
+		}
+	}
+	clear := q.Get("clear") != "false"
+	if err := s.LoadRLE(http.MaxBytesReader(w, r.Body, SERVER_MAX_BODY), ofs[0], ofs[1], clear); err != nil {
+		return err
+	}
+	serverWriteJSON(w, http.StatusOK, s.Info())
+	return nil
+}
+
+// Check that all x,y pairs are in range. See LifeCellInRange
+func serverCheckCells(coords []int64) error {
+	for i := 0; i+1 < len(coords); i = i + 2 {
+		if !LifeCellInRange(coords[i], coords[i+1]) {
+			return newServerError(http.StatusBadRequest, "cell %d,%d is out of range. x and y must be between %d and %d", coords[i], coords[i+1], -LIFE_CELL_MAX, LIFE_CELL_MAX)
+		}
+	}
+	return nil
+}
+
+// Read the x1,y1,x2,y2 query values. The default is all cells
+func serverQueryRect(r *http.Request) ([]int64, error) {
+	q := r.URL.Query()
+	rect := []int64{-indexMult / 2, -indexMult / 2, indexMult / 2, indexMult / 2}
+	for i, name := range []string{"x1", "y1", "x2", "y2"} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, newServerError(http.StatusBadRequest, "%s '%s' must be a number", name, v)
+			}
+			rect[i] = n
+		}
+	}
+	return rect, nil
+}
+
+// Read a JSON body. An empty body is not an error.
+func serverReadJSON(r *http.Request, v interface{}) error {
+	b, err := io.ReadAll(io.LimitReader(r.Body, SERVER_MAX_BODY+1))
+	if err != nil {
+		return newServerError(http.StatusBadRequest, "failed to read the request. %s", err.Error())
+	}
+	if len(b) > SERVER_MAX_BODY {
+		return newServerError(http.StatusRequestEntityTooLarge, "request is larger than %d bytes", SERVER_MAX_BODY)
+	}
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return newServerError(http.StatusBadRequest, "invalid JSON. %s", err.Error())
+	}
+	return nil
+}
+
+func serverWriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func serverWriteError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if se, ok := err.(*serverError); ok {
+		status = se.status
+	}
+	serverWriteJSON(w, status, map[string]string{"error": err.Error()})
+}
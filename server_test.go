@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestServerRemoveCells(t *testing.T) {
+	s, err := NewLifeServer().Create("r", "")
+	if err != nil {
+		t.Fatalf("create failed %s", err.Error())
+	}
+	s.AddCells([]int64{0, 0, 1, 0, 2, 0, 5, 5, 6, 6})
+	// The index is used to find cells. It must not point at removed cells
+	s.lg.GetCell(1, 0)
+	s.RemoveCells([]int64{1, 0, 6, 6, 9, 9, 2})
+	if s.lg.GetCellCount() != 3 || s.lg.CountCells() != 3 || s.lg.GetCell(1, 0) != 0 || s.lg.GetCell(2, 0) != 1 {
+		t.Errorf("remove cells is wrong. Count %d cells %v", s.lg.GetCellCount(), s.lg.ListCellsWithMode(0))
+	}
+}
+
+func TestServerSessions(t *testing.T) {
+	ts := httptest.NewServer(NewLifeServer().Handler())
+	defer ts.Close()
+
+	info := &SessionInfo{}
+	AssertServerRequest(t, ts, "POST", "/sessions", `{"name":"g1"}`, http.StatusCreated, info)
+	if info.Name != "g1" || info.Rule != "B3/S23" || info.Population != 0 || info.Bounds != nil {
+		t.Errorf("created session is wrong %+v", info)
+	}
+	AssertServerRequest(t, ts, "POST", "/sessions", `{"rule":"B36/S23"}`, http.StatusCreated, info)
+	if info.Name != "s1" || info.Rule != "B36/S23" {
+		t.Errorf("created session with a generated name is wrong %+v", info)
+	}
+	AssertServerError(t, ts, "POST", "/sessions", `{"name":"g1"}`, http.StatusConflict, "session 'g1' already exists")
+	AssertServerError(t, ts, "POST", "/sessions", `{"name":"a/b"}`, http.StatusBadRequest, "session name 'a/b' must be 1 to 64 letters, digits, '_', '.' or '-'")
+	AssertServerError(t, ts, "POST", "/sessions", `{"rule":"B3"}`, http.StatusBadRequest, "rule 'B3' must be in the form B3/S23")
+	AssertServerError(t, ts, "POST", "/sessions", `{"name":`, http.StatusBadRequest, "invalid JSON. unexpected end of JSON input")
+	AssertServerError(t, ts, "GET", "/sessions/none", "", http.StatusNotFound, "session 'none' not found")
+	AssertServerError(t, ts, "GET", "/sessions/g1/walk", "", http.StatusNotFound, "GET /sessions/g1/walk not found")
+
+	// Load a glider at 10,10 and run it for 4 generations. It moves 1,1
+	AssertServerRequest(t, ts, "PUT", "/sessions/g1/rle?x=10&y=10", "x = 3, y = 3\nbo$2bo$3o!", http.StatusOK, info)
+	if info.Population != 5 || fmt.Sprint(info.Bounds) != "[10 10 12 12]" {
+		t.Errorf("loaded glider is wrong %+v", info)
+	}
+	AssertServerRequest(t, ts, "POST", "/sessions/g1/step", `{"n":4}`, http.StatusOK, info)
+	if info.Generation != 4 || info.Population != 5 || fmt.Sprint(info.Bounds) != "[11 11 13 13]" {
+		t.Errorf("glider after 4 generations is wrong %+v", info)
+	}
+	AssertServerError(t, ts, "POST", "/sessions/g1/step?n=-1", "", http.StatusBadRequest, "n -1 must be between 0 and 100000")
+	AssertServerError(t, ts, "PUT", "/sessions/g1/rle", "x = 3, y = 3\nbo$2bq!", http.StatusBadRequest, "g1:2:6: unexpected character 'q'. Expected a number, b, o, $ or !")
+
+	cells := &SessionCells{}
+	AssertServerRequest(t, ts, "GET", "/sessions/g1/cells?x1=11&y1=11&x2=12&y2=12", "", http.StatusOK, cells)
+	if fmt.Sprint(cells.Cells) != "[12 11]" || cells.Population != 5 {
+		t.Errorf("cells in bounds are wrong %v", cells.Cells)
+	}
+	AssertServerRequest(t, ts, "DELETE", "/sessions/g1/cells", `{"cells":[12,11]}`, http.StatusOK, info)
+	if info.Population != 4 {
+		t.Errorf("population after remove is wrong %+v", info)
+	}
+	AssertServerRequest(t, ts, "POST", "/sessions/g1/cells", `{"cells":[0,0,1,0]}`, http.StatusOK, info)
+	if info.Population != 6 {
+		t.Errorf("population after remove and add is wrong %+v", info)
+	}
+	AssertServerError(t, ts, "POST", "/sessions/g1/cells", `{"cells":[0]}`, http.StatusBadRequest, "cells must be x,y pairs. Found 1 values")
+	AssertServerError(t, ts, "POST", "/sessions/g1/cells", `{"cells":[0,50000000]}`, http.StatusBadRequest, "cell 0,50000000 is out of range. x and y must be between -49999998 and 49999998")
+	AssertServerError(t, ts, "PUT", "/sessions/g1/rle?clear=false&y=49999998", "x = 1, y = 2\no$o!", http.StatusBadRequest, "cell 0,49999999 is out of range. x and y must be between -49999998 and 49999998")
+	// An empty list does not remove all cells
+	AssertServerError(t, ts, "DELETE", "/sessions/g1/cells", `{"cells":[]}`, http.StatusBadRequest, "no cells to remove. Use {\"clear\":true} to remove all cells")
+	AssertServerError(t, ts, "DELETE", "/sessions/g1/cells", "", http.StatusBadRequest, "no cells to remove. Use {\"clear\":true} to remove all cells")
+	AssertServerError(t, ts, "DELETE", "/sessions/g1/cells", `{"cells":[0,0],"clear":true}`, http.StatusBadRequest, "use cells or clear but not both")
+	AssertServerRequest(t, ts, "GET", "/sessions/g1", "", http.StatusOK, info)
+	if info.Population != 6 {
+		t.Errorf("population after rejected requests is wrong %+v", info)
+	}
+
+	rle := AssertServerRequest(t, ts, "GET", "/sessions/g1/rle", "", http.StatusOK, nil)
+	if !strings.Contains(rle, "x = 14, y = 14, rule = B3/S23\n2o12$13bo$11b3o!\n") {
+		t.Errorf("rle export is wrong:\n%s", rle)
+	}
+
+	AssertServerRequest(t, ts, "DELETE", "/sessions/g1/cells", `{"clear":true}`, http.StatusOK, info)
+	if info.Population != 0 || info.Generation != 0 {
+		t.Errorf("clear is wrong %+v", info)
+	}
+
+	list := make([]*SessionInfo, 0)
+	AssertServerRequest(t, ts, "GET", "/sessions", "", http.StatusOK, &list)
+	if len(list) != 2 || list[0].Name != "g1" || list[1].Name != "s1" {
+		t.Errorf("session list is wrong %v", list)
+	}
+	AssertServerRequest(t, ts, "DELETE", "/sessions/s1", "", http.StatusNoContent, nil)
+	AssertServerRequest(t, ts, "GET", "/sessions", "", http.StatusOK, &list)
+	if len(list) != 1 {
+		t.Errorf("session list after delete is wrong %v", list)
+	}
+}
+
+func TestServerConcurrent(t *testing.T) {
+	ts := httptest.NewServer(NewLifeServer().Handler())
+	defer ts.Close()
+	AssertServerRequest(t, ts, "POST", "/sessions", `{"name":"c"}`, http.StatusCreated, nil)
+	AssertServerRequest(t, ts, "PUT", "/sessions/c/rle", "3o!", http.StatusOK, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				AssertServerRequest(t, ts, "POST", "/sessions/c/step", "", http.StatusOK, nil)
+				AssertServerRequest(t, ts, "GET", "/sessions/c/cells", "", http.StatusOK, nil)
+			}
+		}()
+	}
+	wg.Wait()
+	info := &SessionInfo{}
+	AssertServerRequest(t, ts, "GET", "/sessions/c", "", http.StatusOK, info)
+	if info.Generation != 50 || info.Population != 3 {
+		t.Errorf("concurrent steps are wrong %+v", info)
+	}
+}
+
+// Make a request and check the status. If v is not nil the JSON response is decoded in to it.
+// Returns the response body.
+func AssertServerRequest(t *testing.T, ts *httptest.Server, method, path, body string, status int, v interface{}) string {
+	req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Errorf("%s %s failed %s", method, path, err.Error())
+		return ""
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != status {
+		t.Errorf("%s %s returned %d expected %d. %s", method, path, resp.StatusCode, status, string(b))
+	}
+	if v != nil {
+		if err := json.Unmarshal(b, v); err != nil {
+			t.Errorf("%s %s response is not JSON %s", method, path, string(b))
+		}
+	}
+	return string(b)
+}
+
+func AssertServerError(t *testing.T, ts *httptest.Server, method, path, body string, status int, exp string) {
+	e := make(map[string]string)
+	AssertServerRequest(t, ts, method, path, body, status, &e)
+	if e["error"] != exp {
+		t.Errorf("%s %s error '%s' expected '%s'", method, path, e["error"], exp)
+	}
+}
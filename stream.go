@@ -0,0 +1,233 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	STREAM_MAX_VIEWPORT  = 1000000 // Maximum width * height of a watched viewport
+	STREAM_MIN_DELAY     = 10      // Milliseconds per generation when running in the background
+	STREAM_DEFAULT_DELAY = 100
+	STREAM_KEEP_ALIVE    = 15 * time.Second // Send a comment so proxies do not close an idle stream
+)
+
+//go:embed viewer.html
+var viewerHtml []byte
+
+// A browser watching a viewport of a session.
+//
+//	frames has a capacity of 1. Only the latest frame is kept so a slow watcher skips generations.
+//	closed is closed when the session is deleted.
+type sessionWatcher struct {
+	x1, y1, x2, y2 int64
+	frames         chan *streamFrame
+	closed         chan bool
+}
+
+// The cells in a watchers viewport after a generation
+type streamFrame struct {
+	gen        int
+	population int
+	cells      []int64
+}
+
+// The data sent to a watcher.
+// The first event is a 'snapshot' with all the cells in the viewport.
+// Each following event is a 'delta' with the cells born and the cells that died since the previous event.
+type StreamEvent struct {
+	Gen        int     `json:"gen"`
+	Population int     `json:"population"`
+	Cells      []int64 `json:"cells,omitempty"` // x,y pairs. Snapshot only
+	Born       []int64 `json:"born,omitempty"`  // x,y pairs. Delta only
+	Died       []int64 `json:"died,omitempty"`  // x,y pairs. Delta only
+}
+
+// Send the current cells to every watcher. The mutex must be held
+func (s *LifeSession) notify() {
+	for w := range s.watchers {
+		w.offer(s.frame(w))
+	}
+}
+
+// The mutex must be held
+func (s *LifeSession) frame(w *sessionWatcher) *streamFrame {
+	f := &streamFrame{gen: s.lg.GetGenerationCount(), population: s.lg.CountCells(), cells: make([]int64, 0)}
+	s.lg.CellsInBounds(w.x1, w.y1, w.x2, w.y2, func(lc *LifeCell) {
+		f.cells = append(f.cells, lc.x, lc.y)
+	})
+	return f
+}
+
+// Replace any frame that has not been sent with the new frame. Never blocks.
+func (w *sessionWatcher) offer(f *streamFrame) {
+	select {
+	case <-w.frames:
+	default:
+	}
+	select {
+	case w.frames <- f:
+	default:
+	}
+}
+
+func (s *LifeSession) addWatcher(x1, y1, x2, y2 int64) *sessionWatcher {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := &sessionWatcher{x1: x1, y1: y1, x2: x2, y2: y2, frames: make(chan *streamFrame, 1), closed: make(chan bool)}
+	w.offer(s.frame(w))
+	s.watchers[w] = true
+	return w
+}
+
+func (s *LifeSession) removeWatcher(w *sessionWatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.watchers, w)
+}
+
+func (s *LifeSession) closeWatchers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for w := range s.watchers {
+		close(w.closed)
+	}
+	s.watchers = make(map[*sessionWatcher]bool)
+}
+
+// Run in the background with a delay between generations. If already running the delay is changed.
+func (s *LifeSession) Run(delay time.Duration) {
+	s.Stop()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stop := make(chan bool)
+	s.stop = stop
+	go func() {
+		ticker := time.NewTicker(delay)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				if s.stop != stop { // Stopped while waiting for the lock
+					s.mu.Unlock()
+					return
+				}
+				s.lg.SetRunFor(RUN_FOR_EVER, nil)
+				s.lg.NextGen()
+				s.notify()
+				s.mu.Unlock()
+			}
+		}
+	}()
+}
+
+func (s *LifeSession) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+func (s *LifeSession) handleRun(w http.ResponseWriter, r *http.Request) error {
+	delay := STREAM_DEFAULT_DELAY
+	if v := r.URL.Query().Get("delay"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < STREAM_MIN_DELAY {
+			return newServerError(http.StatusBadRequest, "delay '%s' must be a number of at least %d", v, STREAM_MIN_DELAY)
+		}
+		delay = n
+	}
+	s.Run(time.Duration(delay) * time.Millisecond)
+	serverWriteJSON(w, http.StatusOK, s.Info())
+	return nil
+}
+
+// Stream Server Sent Events for a viewport until the client disconnects or the session is deleted.
+func (s *LifeSession) handleWatch(w http.ResponseWriter, r *http.Request) error {
+	rect, err := serverQueryRect(r)
+	if err != nil {
+		return err
+	}
+	width := rect[2] - rect[0] + 1
+	height := rect[3] - rect[1] + 1
+	if width < 1 || height < 1 || width > STREAM_MAX_VIEWPORT || height > STREAM_MAX_VIEWPORT || width*height > STREAM_MAX_VIEWPORT {
+		return newServerError(http.StatusBadRequest, "viewport %dx%d must not be empty or larger than %d cells", width, height, STREAM_MAX_VIEWPORT)
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return newServerError(http.StatusInternalServerError, "streaming is not supported")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	watcher := s.addWatcher(rect[0], rect[1], rect[2], rect[3])
+	defer s.removeWatcher(watcher)
+	keepAlive := time.NewTicker(STREAM_KEEP_ALIVE)
+	defer keepAlive.Stop()
+	var prev []int64 // nil until the snapshot has been sent
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-watcher.closed:
+			fmt.Fprint(w, "event: closed\ndata: {}\n\n")
+			flusher.Flush()
+			return nil
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep alive\n\n")
+		case f := <-watcher.frames:
+			ev := &StreamEvent{Gen: f.gen, Population: f.population}
+			name := "delta"
+			if prev == nil {
+				name = "snapshot"
+				ev.Cells = f.cells
+			} else {
+				streamDelta(prev, f.cells, ev)
+			}
+			prev = f.cells
+			b, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b)
+		}
+		flusher.Flush()
+	}
+}
+
+// Compare the cells with the previous cells, filling in Born and Died.
+func streamDelta(prev, cells []int64, ev *StreamEvent) {
+	prevSet := streamCellSet(prev)
+	nextSet := streamCellSet(cells)
+	for i := 0; i < len(cells); i = i + 2 {
+		if !prevSet[[2]int64{cells[i], cells[i+1]}] {
+			ev.Born = append(ev.Born, cells[i], cells[i+1])
+		}
+	}
+	for i := 0; i < len(prev); i = i + 2 {
+		if !nextSet[[2]int64{prev[i], prev[i+1]}] {
+			ev.Died = append(ev.Died, prev[i], prev[i+1])
+		}
+	}
+}
+
+func streamCellSet(cells []int64) map[[2]int64]bool {
+	set := make(map[[2]int64]bool, len(cells)/2)
+	for i := 0; i < len(cells); i = i + 2 {
+		set[[2]int64{cells[i], cells[i+1]}] = true
+	}
+	return set
+}
+
+func handleViewer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(viewerHtml)
+}
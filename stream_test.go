@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamDelta(t *testing.T) {
+	ev := &StreamEvent{}
+	streamDelta([]int64{0, 1, 1, 1, 2, 1}, []int64{1, 0, 1, 1, 1, 2}, ev)
+	if fmt.Sprint(ev.Born) != "[1 0 1 2]" || fmt.Sprint(ev.Died) != "[0 1 2 1]" {
+		t.Errorf("blinker delta is wrong born %v died %v", ev.Born, ev.Died)
+	}
+}
+
+func TestStreamWatch(t *testing.T) {
+	ls := NewLifeServer()
+	ts := httptest.NewServer(ls.Handler())
+	defer ts.Close()
+	AssertServerRequest(t, ts, "POST", "/sessions", `{"name":"w"}`, http.StatusCreated, nil)
+	AssertServerRequest(t, ts, "PUT", "/sessions/w/rle?y=1", "3o!", http.StatusOK, nil)
+	AssertServerError(t, ts, "GET", "/sessions/w/watch?x1=0&y1=0&x2=2000&y2=2000", "", http.StatusBadRequest, "viewport 2001x2001 must not be empty or larger than 1000000 cells")
+	AssertServerError(t, ts, "GET", "/sessions/w/watch?x1=5&x2=4", "", http.StatusBadRequest, "viewport 0x100000001 must not be empty or larger than 1000000 cells")
+
+	// The viewport only includes the top two rows of the blinker
+	resp, err := http.Get(ts.URL + "/sessions/w/watch?x1=0&y1=0&x2=2&y2=1")
+	if err != nil {
+		t.Fatalf("watch failed %s", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("watch content type is wrong %s", resp.Header.Get("Content-Type"))
+	}
+	events := make(chan string, 10)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		ev := ""
+		for sc.Scan() {
+			line := sc.Text()
+			if line == "" {
+				events <- ev
+				ev = ""
+			} else if !strings.HasPrefix(line, ":") {
+				ev = ev + line + "\n"
+			}
+		}
+		close(events)
+	}()
+
+	AssertStreamEvent(t, events, "snapshot", &StreamEvent{Gen: 0, Population: 3, Cells: []int64{0, 1, 1, 1, 2, 1}})
+	AssertServerRequest(t, ts, "POST", "/sessions/w/step", "", http.StatusOK, nil)
+	AssertStreamEvent(t, events, "delta", &StreamEvent{Gen: 1, Population: 3, Born: []int64{1, 0}, Died: []int64{0, 1, 2, 1}})
+
+	info := &SessionInfo{}
+	AssertServerRequest(t, ts, "POST", "/sessions/w/run?delay=10", "", http.StatusOK, info)
+	if !info.Running || info.Watchers != 1 {
+		t.Errorf("session should be running with 1 watcher %+v", info)
+	}
+	AssertStreamEvent(t, events, "delta", nil)
+	AssertServerRequest(t, ts, "POST", "/sessions/w/stop", "", http.StatusOK, info)
+	if info.Running {
+		t.Errorf("session should be stopped %+v", info)
+	}
+	AssertServerError(t, ts, "POST", "/sessions/w/run?delay=1", "", http.StatusBadRequest, "delay '1' must be a number of at least 10")
+
+	AssertServerRequest(t, ts, "DELETE", "/sessions/w", "", http.StatusNoContent, nil)
+	for ev := range events {
+		if strings.HasPrefix(ev, "event: closed\n") {
+			return
+		}
+	}
+	t.Errorf("watch should get a closed event when the session is deleted")
+}
+
+func TestStreamViewer(t *testing.T) {
+	ts := httptest.NewServer(NewLifeServer().Handler())
+	defer ts.Close()
+	html := AssertServerRequest(t, ts, "GET", "/viewer?session=a", "", http.StatusOK, nil)
+	if !strings.Contains(html, "new EventSource(") {
+		t.Errorf("viewer should use EventSource")
+	}
+}
+
+// Wait for the next event and check its name. If exp is not nil the data must match
+func AssertStreamEvent(t *testing.T, events chan string, name string, exp *StreamEvent) {
+	select {
+	case ev := <-events:
+		prefix := "event: " + name + "\ndata: "
+		if !strings.HasPrefix(ev, prefix) {
+			t.Errorf("event should be %s. Got %s", name, ev)
+			return
+		}
+		if exp == nil {
+			return
+		}
+		b, _ := json.Marshal(exp)
+		if strings.TrimSpace(ev[len(prefix):]) != string(b) {
+			t.Errorf("event data is %s expected %s", ev[len(prefix):], string(b))
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("timed out waiting for a %s event", name)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type LifeSymmetry int
+
+// The 8 symmetries of a square grid (the rotations and reflections).
+// Rotations are clockwise as seen on the screen (y increases downwards).
+const (
+	SYM_NONE    LifeSymmetry = 0 // x, y
+	SYM_ROT_90  LifeSymmetry = 1 // -y, x
+	SYM_ROT_180 LifeSymmetry = 2 // -x, -y
+	SYM_ROT_270 LifeSymmetry = 3 // y, -x
+	SYM_FLIP_X  LifeSymmetry = 4 // -x, y   Mirror left to right
+	SYM_FLIP_Y  LifeSymmetry = 5 // x, -y   Mirror top to bottom
+	SYM_FLIP_XY LifeSymmetry = 6 // y, x    Mirror about the leading diagonal
+	SYM_FLIP_YX LifeSymmetry = 7 // -y, -x  Mirror about the other diagonal
+	SYM_COUNT                = 8
+)
+
+var symmetryNames = []string{"none", "rot90", "rot180", "rot270", "flipx", "flipy", "flipxy", "flipyx"}
+
+func (s LifeSymmetry) String() string {
+	if s < 0 || int(s) >= len(symmetryNames) {
+		return fmt.Sprintf("sym(%d)", int(s))
+	}
+	return symmetryNames[s]
+}
+
+// Parse the name of a symmetry as returned by String()
+func ParseSymmetry(name string) (LifeSymmetry, error) {
+	n := strings.ToLower(strings.TrimSpace(name))
+	for i, s := range symmetryNames {
+		if s == n {
+			return LifeSymmetry(i), nil
+		}
+	}
+	return SYM_NONE, fmt.Errorf("unknown rotation or flip '%s'. Use one of %s", name, strings.Join(symmetryNames, ","))
+}
+
+// Apply the symmetry to a single cell position
+func (s LifeSymmetry) Apply(x, y int64) (int64, int64) {
+	switch s {
+	case SYM_ROT_90:
+		return -y, x
+	case SYM_ROT_180:
+		return -x, -y
+	case SYM_ROT_270:
+		return y, -x
+	case SYM_FLIP_X:
+		return -x, y
+	case SYM_FLIP_Y:
+		return x, -y
+	case SYM_FLIP_XY:
+		return y, x
+	case SYM_FLIP_YX:
+		return -y, -x
+	}
+	return x, y
+}
+
+// The symmetry that undoes this one.
+// Only the 90 and 270 degree rotations are not their own inverse.
+func (s LifeSymmetry) Inverse() LifeSymmetry {
+	switch s {
+	case SYM_ROT_90:
+		return SYM_ROT_270
+	case SYM_ROT_270:
+		return SYM_ROT_90
+	}
+	return s
+}
+
+// Transform a list of x,y coords using the symmetry.
+// The result is normalised (see POCNormaliseCoords) so the min x and y are 0.
+// Returns the transformed coords and the width and height.
+func TransformCoords(in []int64, s LifeSymmetry) ([]int64, int64, int64) {
+	out := make([]int64, len(in))
+	for i := 0; i < len(in); i = i + 2 {
+		out[i], out[i+1] = s.Apply(in[i], in[i+1])
+	}
+	return POCNormaliseCoords(out)
+}
+
+/*
+Rotate or flip the cells with mode matching mask (see ListCellsWithMode) about the centre of their bounding box.
+mask must not be 0. The moved cells are given the mode mask. Where a moved cell lands on an existing cell the existing cell is kept.
+Returns the number of cells moved.
+*/
+func (lg *LifeGen) TransformCellsWithMode(mask int, s LifeSymmetry) int {
+	cells := lg.ListCellsWithMode(mask)
+	if len(cells) == 0 {
+		return 0
+	}
+	x1, y1 := apgMinXY(cells)
+	_, w, h := POCNormaliseCoords(cells)
+	moved, nw, nh := TransformCoords(cells, s)
+	lg.RemoveCellsWithMode(mask)
+	lg.AddCellsAtOffset(x1+(w-nw)/2, y1+(h-nh)/2, mask, moved)
+	return len(moved) / 2
+}
+
+/*
+Move the cells with mode matching mask by dx, dy. mask must not be 0.
+Returns the number of cells moved.
+*/
+func (lg *LifeGen) MoveCellsWithMode(mask int, dx, dy int64) int {
+	cells := lg.ListCellsWithMode(mask)
+	lg.RemoveCellsWithMode(mask)
+	lg.AddCellsAtOffset(dx, dy, mask, cells)
+	return len(cells) / 2
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	UNDO_MAX = 100 // Oldest commands are dropped when there are more than this
+)
+
+/*
+A change to a LifeGen that can be undone and done again.
+Undo is only called after the change has been made (or after Redo) so the LifeGen is in the state the command left it.
+*/
+type LifeCommand interface {
+	Undo(lg *LifeGen)
+	Redo(lg *LifeGen)
+	Name() string
+}
+
+/*
+Cells added and removed by an edit.
+
+	Only the differences are kept. Small edits (toggle, paste and delete) record their own cells with NewLifeCellsChange
+	so toggling one cell in a large universe is cheap. Other edits compare all of the cells with NewLifeEdit.
+	Cells added by Undo or Redo have mode 0 so any selection is lost.
+*/
+type LifeCellsCommand struct {
+	name    string
+	Added   []int64
+	Removed []int64
+}
+
+// A change of rule. The cells are not changed.
+type LifeRuleCommand struct {
+	Before *LifeRule
+	After  *LifeRule
+}
+
+// A change to the generation count. For example a run, or a clear that starts again at 0.
+type LifeGenerationCommand struct {
+	name   string
+	Before int
+	After  int
+}
+
+// Several commands done as one. Undo is in reverse order.
+type LifeCommandList []LifeCommand
+
+/*
+An undo and a redo stack of commands.
+
+	Push a command after each change. Undo moves the last command to the redo stack and Redo moves it back.
+	A Push after an Undo clears the redo stack.
+	Commands can be pushed from the animation goroutine (the end of a run) so all methods are safe to call concurrently.
+*/
+type LifeUndo struct {
+	mu     sync.Mutex
+	done   []LifeCommand
+	undone []LifeCommand
+	max    int
+}
+
+func NewLifeUndo(max int) *LifeUndo {
+	return &LifeUndo{done: make([]LifeCommand, 0), undone: make([]LifeCommand, 0), max: max}
+}
+
+// Record a command that has already been done. nil is ignored
+func (u *LifeUndo) Push(cmd LifeCommand) {
+	if cmd == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.done = append(u.done, cmd)
+	if len(u.done) > u.max {
+		u.done = u.done[len(u.done)-u.max:]
+	}
+	u.undone = u.undone[:0]
+}
+
+// Undo the last command. Returns nil if there is nothing to undo
+func (u *LifeUndo) Undo(lg *LifeGen) LifeCommand {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.done) == 0 {
+		return nil
+	}
+	cmd := u.done[len(u.done)-1]
+	u.done = u.done[:len(u.done)-1]
+	cmd.Undo(lg)
+	u.undone = append(u.undone, cmd)
+	return cmd
+}
+
+// Do the last undone command again. Returns nil if there is nothing to redo
+func (u *LifeUndo) Redo(lg *LifeGen) LifeCommand {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.undone) == 0 {
+		return nil
+	}
+	cmd := u.undone[len(u.undone)-1]
+	u.undone = u.undone[:len(u.undone)-1]
+	cmd.Redo(lg)
+	u.done = append(u.done, cmd)
+	return cmd
+}
+
+// The number of commands that can be undone and redone
+func (u *LifeUndo) Counts() (int, int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.done), len(u.undone)
+}
+
+func (u *LifeUndo) Clear() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.done = u.done[:0]
+	u.undone = u.undone[:0]
+}
+
+/*
+The command for a change from the cells in before to the cells in after (both x,y pairs as returned by ListCellsWithMode).
+The rules can be nil if the rule was not changed. genBefore and genAfter are the generation counts. See GetGenerationCount.
+Returns nil if nothing changed.
+*/
+func NewLifeEdit(name string, before, after []int64, ruleBefore, ruleAfter *LifeRule, genBefore, genAfter int) LifeCommand {
+	list := make(LifeCommandList, 0)
+	if cells := NewLifeCellsCommand(name, before, after); cells != nil {
+		list = append(list, cells)
+	}
+	if ruleBefore != nil && ruleAfter != nil && ruleBefore.String() != ruleAfter.String() {
+		list = append(list, &LifeRuleCommand{Before: ruleBefore, After: ruleAfter})
+	}
+	if genBefore != genAfter {
+		list = append(list, &LifeGenerationCommand{name: name, Before: genBefore, After: genAfter})
+	}
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	}
+	return list
+}
+
+// The cells that are in after and not in before are added. The cells in before and not in after are removed. Returns nil if there are none
+func NewLifeCellsCommand(name string, before, after []int64) *LifeCellsCommand {
+	inBefore := undoCellSet(before)
+	inAfter := undoCellSet(after)
+	cmd := &LifeCellsCommand{name: name, Added: make([]int64, 0), Removed: make([]int64, 0)}
+	for i := 0; i < len(after); i = i + 2 {
+		if !inBefore[[2]int64{after[i], after[i+1]}] {
+			cmd.Added = append(cmd.Added, after[i], after[i+1])
+		}
+	}
+	for i := 0; i < len(before); i = i + 2 {
+		if !inAfter[[2]int64{before[i], before[i+1]}] {
+			cmd.Removed = append(cmd.Removed, before[i], before[i+1])
+		}
+	}
+	if len(cmd.Added) == 0 && len(cmd.Removed) == 0 {
+		return nil
+	}
+	return cmd
+}
+
+// The command for cells that have already been added and removed. Returns nil if there are none
+func NewLifeCellsChange(name string, added, removed []int64) LifeCommand {
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return &LifeCellsCommand{name: name, Added: added, Removed: removed}
+}
+
+func (c *LifeCellsCommand) Undo(lg *LifeGen) {
+	lg.RemoveCells(c.Added)
+	lg.AddCellsAtOffset(0, 0, 0, c.Removed)
+}
+
+func (c *LifeCellsCommand) Redo(lg *LifeGen) {
+	lg.RemoveCells(c.Removed)
+	lg.AddCellsAtOffset(0, 0, 0, c.Added)
+}
+
+func (c *LifeCellsCommand) Name() string {
+	return c.name
+}
+
+func (c *LifeRuleCommand) Undo(lg *LifeGen) {
+	lg.SetRule(c.Before)
+}
+
+func (c *LifeRuleCommand) Redo(lg *LifeGen) {
+	lg.SetRule(c.After)
+}
+
+func (c *LifeRuleCommand) Name() string {
+	return fmt.Sprintf("Rule %s", c.After.String())
+}
+
+func (c *LifeGenerationCommand) Undo(lg *LifeGen) {
+	lg.SetGenerationCount(c.Before)
+}
+
+func (c *LifeGenerationCommand) Redo(lg *LifeGen) {
+	lg.SetGenerationCount(c.After)
+}
+
+func (c *LifeGenerationCommand) Name() string {
+	return c.name
+}
+
+func (l LifeCommandList) Undo(lg *LifeGen) {
+	for i := len(l) - 1; i >= 0; i-- {
+		l[i].Undo(lg)
+	}
+}
+
+func (l LifeCommandList) Redo(lg *LifeGen) {
+	for _, c := range l {
+		c.Redo(lg)
+	}
+}
+
+func (l LifeCommandList) Name() string {
+	return l[0].Name()
+}
+
+func undoCellSet(cells []int64) map[[2]int64]bool {
+	set := make(map[[2]int64]bool, len(cells)/2)
+	for i := 0; i < len(cells); i = i + 2 {
+		set[[2]int64{cells[i], cells[i+1]}] = true
+	}
+	return set
+}
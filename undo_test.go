@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLifeUndoCells(t *testing.T) {
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	u := NewLifeUndo(UNDO_MAX)
+	AssertLifeEdit(t, u, lg, "Add", func() {
+		lg.AddCellsAtOffset(0, 0, 0, []int64{0, 0, 1, 0, 2, 0})
+	})
+	AssertLifeEdit(t, u, lg, "Toggle", func() {
+		lg.RemoveCell(0, 0)
+		lg.AddCell(3, 0, 0)
+	})
+	AssertLifeEdit(t, u, lg, "Run", func() {
+		lg.NextGen()
+	})
+	testGen(t, lg, "After edits:", "2,-1 2,0 2,1")
+
+	AssertUndo(t, u, lg, false, "Run", "1,0 2,0 3,0")
+	if lg.GetGenerationCount() != 0 {
+		t.Errorf("Undo Run: Expected generation 0. Actual %d", lg.GetGenerationCount())
+	}
+	AssertUndo(t, u, lg, true, "Run", "2,-1 2,0 2,1")
+	if lg.GetGenerationCount() != 1 {
+		t.Errorf("Redo Run: Expected generation 1. Actual %d", lg.GetGenerationCount())
+	}
+	AssertUndo(t, u, lg, false, "Run", "1,0 2,0 3,0")
+	AssertUndo(t, u, lg, false, "Toggle", "0,0 1,0 2,0")
+	AssertUndo(t, u, lg, true, "Toggle", "1,0 2,0 3,0")
+	AssertUndo(t, u, lg, false, "Toggle", "0,0 1,0 2,0")
+	AssertUndo(t, u, lg, false, "Add", "None")
+	if u.Undo(lg) != nil {
+		t.Errorf("Undo: Expected nil when there is nothing to undo")
+	}
+	if done, undone := u.Counts(); done != 0 || undone != 3 {
+		t.Errorf("Counts: Expected 0, 3. Actual %d, %d", done, undone)
+	}
+	// A new edit clears the redo stack
+	AssertLifeEdit(t, u, lg, "Other", func() {
+		lg.AddCell(9, 9, 0)
+	})
+	if u.Redo(lg) != nil {
+		t.Errorf("Redo: Expected nil after a new edit")
+	}
+	// No change is not pushed
+	if NewLifeEdit("None", lg.ListCellsWithMode(0), lg.ListCellsWithMode(0), lg.GetRule(), lg.GetRule(), 3, 3) != nil || NewLifeCellsChange("None", nil, []int64{}) != nil {
+		t.Errorf("NewLifeEdit: Expected nil for no change")
+	}
+}
+
+func TestLifeUndoCellsChange(t *testing.T) {
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	u := NewLifeUndo(UNDO_MAX)
+	lg.AddCellsAtOffset(0, 0, SELECT_MODE_MASK, []int64{0, 0, 1, 0})
+	// Only the new cells are recorded so undo does not remove 1,0
+	added := lg.AddNewCells(1, 0, 0, []int64{0, 0, 1, 0, 2, 0})
+	if fmt.Sprint(added) != "[2 0 3 0]" {
+		t.Errorf("AddNewCells: Expected [2 0 3 0]. Actual %v", added)
+	}
+	u.Push(NewLifeCellsChange("Paste", added, nil))
+	u.Push(NewLifeCellsChange("Delete", nil, lg.RemoveCellsWithMode(SELECT_MODE_MASK)))
+	testGen(t, lg, "Delete:", "2,0 3,0")
+	AssertUndo(t, u, lg, false, "Delete", "0,0 1,0 2,0 3,0")
+	AssertUndo(t, u, lg, false, "Paste", "0,0 1,0")
+	AssertUndo(t, u, lg, true, "Paste", "0,0 1,0 2,0 3,0")
+	if removed := lg.RemoveCells([]int64{3, 0, 9, 9}); fmt.Sprint(removed) != "[3 0]" {
+		t.Errorf("RemoveCells: Expected [3 0]. Actual %v", removed)
+	}
+}
+
+func TestLifeUndoGeneration(t *testing.T) {
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	u := NewLifeUndo(UNDO_MAX)
+	lg.AddCellsAtOffset(0, 0, 0, []int64{0, 0, 1, 0, 0, 1, 1, 1})
+	// A block does not change so only the generation count is recorded
+	AssertLifeEdit(t, u, lg, "Run", func() {
+		lg.Step(5)
+	})
+	if _, ok := u.done[0].(*LifeGenerationCommand); !ok {
+		t.Errorf("Run: Expected a generation command. Actual %T", u.done[0])
+	}
+	AssertLifeEdit(t, u, lg, "Clear", lg.Reset)
+	AssertUndo(t, u, lg, false, "Clear", "0,0 0,1 1,0 1,1")
+	if lg.GetGenerationCount() != 5 {
+		t.Errorf("Undo Clear: Expected generation 5. Actual %d", lg.GetGenerationCount())
+	}
+	AssertUndo(t, u, lg, false, "Run", "0,0 0,1 1,0 1,1")
+	if lg.GetGenerationCount() != 0 {
+		t.Errorf("Undo Run: Expected generation 0. Actual %d", lg.GetGenerationCount())
+	}
+}
+
+func TestLifeUndoRule(t *testing.T) {
+	lg := NewLifeGen(nil, RUN_FOR_EVER)
+	u := NewLifeUndo(2)
+	highLife, _ := ParseLifeRule("B36/S23")
+	AssertLifeEdit(t, u, lg, "Script", func() {
+		lg.AddCell(1, 1, 0)
+		lg.SetRule(highLife)
+	})
+	if _, ok := u.done[0].(LifeCommandList); !ok {
+		t.Errorf("Script: Expected a list of commands for a cell and rule change. Actual %T", u.done[0])
+	}
+	AssertUndo(t, u, lg, false, "Script", "None")
+	if !lg.GetRule().IsConway() {
+		t.Errorf("Undo: Expected the rule to be B3/S23. Actual %s", lg.GetRule().String())
+	}
+	AssertUndo(t, u, lg, true, "Script", "1,1")
+	if lg.GetRule().String() != "B36/S23" {
+		t.Errorf("Redo: Expected the rule to be B36/S23. Actual %s", lg.GetRule().String())
+	}
+	// Only the last 2 commands are kept
+	AssertLifeEdit(t, u, lg, "A", func() { lg.AddCell(2, 2, 0) })
+	AssertLifeEdit(t, u, lg, "B", func() { lg.AddCell(3, 3, 0) })
+	if done, _ := u.Counts(); done != 2 || u.done[0].Name() != "A" {
+		t.Errorf("Max: Expected 2 commands starting with A. Actual %d", done)
+	}
+}
+
+func AssertLifeEdit(t *testing.T, u *LifeUndo, lg *LifeGen, name string, f func()) {
+	before := lg.ListCellsWithMode(0)
+	rule := lg.GetRule()
+	gen := lg.GetGenerationCount()
+	f()
+	cmd := NewLifeEdit(name, before, lg.ListCellsWithMode(0), rule, lg.GetRule(), gen, lg.GetGenerationCount())
+	if cmd == nil {
+		t.Fatalf("%s: Expected a command", name)
+	}
+	u.Push(cmd)
+}
+
+func AssertUndo(t *testing.T, u *LifeUndo, lg *LifeGen, redo bool, name, exp string) {
+	var cmd LifeCommand
+	if redo {
+		cmd = u.Redo(lg)
+	} else {
+		cmd = u.Undo(lg)
+	}
+	if cmd == nil || cmd.Name() != name {
+		t.Errorf("Undo: Expected command %s. Actual %v", name, cmd)
+		return
+	}
+	testGen(t, lg, cmd.Name()+":", exp)
+	if lg.GetCellCount() != lg.CountCells() {
+		t.Errorf("%s: Cell count %d does not match %d cells", name, lg.GetCellCount(), lg.CountCells())
+	}
+}